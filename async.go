@@ -0,0 +1,114 @@
+package golog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type OverflowPolicy int
+
+const (
+	OverflowBlock OverflowPolicy = iota
+	OverflowDrop
+)
+
+type asyncEntry struct {
+	level   Level
+	content []byte
+	flushed chan struct{}
+}
+
+// AsyncBackend queues log entries and writes them into the wrapped backend
+// from a single goroutine.
+type AsyncBackend struct {
+	mutex    sync.RWMutex
+	backend  Backend
+	queue    chan asyncEntry
+	policy   OverflowPolicy
+	closed   bool
+	dropped  uint64
+	consumed chan struct{}
+}
+
+func NewAsyncBackend(b Backend, queueSize int) *AsyncBackend {
+	asyncBackend := &AsyncBackend{
+		backend:  b,
+		queue:    make(chan asyncEntry, queueSize),
+		consumed: make(chan struct{}),
+	}
+	go asyncBackend.consume()
+	return asyncBackend
+}
+
+func (s *AsyncBackend) SetOverflowPolicy(policy OverflowPolicy) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.policy = policy
+}
+
+func (s *AsyncBackend) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *AsyncBackend) consume() {
+	defer close(s.consumed)
+	for entry := range s.queue {
+		if entry.flushed != nil {
+			s.backend.Flush()
+			close(entry.flushed)
+			continue
+		}
+		s.backend.Log(entry.level, entry.content)
+	}
+}
+
+func (s *AsyncBackend) Log(level Level, content []byte) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.closed {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	entry := asyncEntry{
+		level:   level,
+		content: append([]byte(nil), content...),
+	}
+	if s.policy == OverflowBlock {
+		s.queue <- entry
+		return
+	}
+	select {
+	case s.queue <- entry:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Flush waits until the queued entries are written, then flushes the
+// wrapped backend.
+func (s *AsyncBackend) Flush() {
+	s.mutex.RLock()
+	if s.closed {
+		s.mutex.RUnlock()
+		return
+	}
+	flushed := make(chan struct{})
+	s.queue <- asyncEntry{flushed: flushed}
+	s.mutex.RUnlock()
+	<-flushed
+}
+
+// Close drains the queue before closing the wrapped backend.
+func (s *AsyncBackend) Close() {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.queue)
+	s.mutex.Unlock()
+
+	<-s.consumed
+	s.backend.Close()
+}
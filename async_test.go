@@ -0,0 +1,83 @@
+package golog
+
+import (
+	"fmt"
+	"testing"
+)
+
+type blockingBackend struct {
+	MemoryBackend
+	release chan struct{}
+}
+
+func (s *blockingBackend) Log(level Level, content []byte) {
+	<-s.release
+	s.MemoryBackend.Log(level, content)
+}
+
+func TestAsyncBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*AsyncBackend)(nil)
+}
+
+func TestAsyncBackendDrop(t *testing.T) {
+	backend := &blockingBackend{release: make(chan struct{})}
+	asyncBackend := NewAsyncBackend(backend, 2)
+	asyncBackend.SetOverflowPolicy(OverflowDrop)
+
+	total := 10
+	for i := 0; i < total; i++ {
+		asyncBackend.Log(Info, []byte(fmt.Sprintf("line %d", i)))
+	}
+	dropped := int(asyncBackend.Dropped())
+	if dropped < total-3 {
+		t.Errorf("at least %v entries should be dropped, actual: %v", total-3, dropped)
+	}
+
+	close(backend.release)
+	asyncBackend.Close()
+	entries := backend.Entries()
+	if len(entries)+dropped != total {
+		t.Errorf("written %v plus dropped %v should be %v", len(entries), dropped, total)
+	}
+	for i, entry := range entries {
+		if expect := fmt.Sprintf("line %d", i); string(entry.Content) != expect {
+			t.Errorf("entry not match, expect: %s, actual: %s", expect, entry.Content)
+		}
+	}
+}
+
+func TestAsyncBackendCloseDrains(t *testing.T) {
+	backend := NewMemoryBackend()
+	asyncBackend := NewAsyncBackend(backend, 100)
+
+	total := 50
+	for i := 0; i < total; i++ {
+		asyncBackend.Log(Info, []byte(fmt.Sprintf("line %d", i)))
+	}
+	asyncBackend.Close()
+
+	entries := backend.Entries()
+	if len(entries) != total {
+		t.Fatalf("count of entries should be %v, actual: %v", total, len(entries))
+	}
+	for i, entry := range entries {
+		if expect := fmt.Sprintf("line %d", i); string(entry.Content) != expect {
+			t.Errorf("entry not match, expect: %s, actual: %s", expect, entry.Content)
+		}
+	}
+	if asyncBackend.Dropped() != 0 {
+		t.Errorf("nothing should be dropped, actual: %v", asyncBackend.Dropped())
+	}
+}
+
+func TestAsyncBackendFlush(t *testing.T) {
+	backend := NewMemoryBackend()
+	asyncBackend := NewAsyncBackend(backend, 100)
+	defer asyncBackend.Close()
+
+	asyncBackend.Log(Info, []byte("This is one string."))
+	asyncBackend.Flush()
+	if entries := backend.Entries(); len(entries) != 1 {
+		t.Errorf("entries should be written after flush, actual: %v", entries)
+	}
+}
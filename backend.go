@@ -0,0 +1,50 @@
+package golog
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backend is the sink that log content is finally written to.
+type Backend interface {
+	Log(level Level, content []byte)
+	Flush()
+	Close()
+}
+
+// LevelEnabler is implemented by backends which drop some levels, so callers
+// can skip building messages that are never written.
+type LevelEnabler interface {
+	IsLevelEnabled(level Level) bool
+}
+
+// NopBackend discards everything logged into it.
+type NopBackend struct{}
+
+func (NopBackend) Log(level Level, content []byte) {
+}
+
+func (NopBackend) Flush() {
+}
+
+func (NopBackend) Close() {
+}
+
+// closeWithTimeout runs close and returns an error if it does not finish
+// within d, close keeps running in the background then.
+func closeWithTimeout(close func(), d time.Duration) error {
+	// buffered so the goroutine exits after a timeout.
+	done := make(chan struct{}, 1)
+	go func() {
+		close()
+		done <- struct{}{}
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("close not finished in %v", d)
+	}
+}
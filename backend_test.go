@@ -0,0 +1,37 @@
+package golog
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestNopBackend(t *testing.T) {
+	var backend Backend = NopBackend{}
+	backend.Log(Info, []byte("This is one string."))
+	backend.Flush()
+	backend.Close()
+}
+
+func TestCloseWithTimeoutExits(t *testing.T) {
+	before := runtime.NumGoroutine()
+	release := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		if err := closeWithTimeout(func() { <-release }, time.Millisecond); err == nil {
+			t.Fatalf("close should time out")
+		}
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second * 2)
+	for {
+		after := runtime.NumGoroutine()
+		if after <= before {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked, before: %v, after: %v", before, after)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
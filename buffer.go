@@ -0,0 +1,33 @@
+package golog
+
+import "sync"
+
+const (
+	defaultPoolBufferSize = 256
+	maxPoolBufferSize     = 64 * 1024
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, 0, defaultPoolBufferSize)
+		return &buffer
+	},
+}
+
+// GetBuffer returns an empty buffer from the pool, callers build a line in it
+// and give it back by PutBuffer once it has been logged.
+func GetBuffer() *[]byte {
+	buffer := bufferPool.Get().(*[]byte)
+	*buffer = (*buffer)[:0]
+	return buffer
+}
+
+// PutBuffer returns buffer to the pool, the buffer must not be used anymore.
+// Large buffers are dropped so the pool does not hold on to them.
+func PutBuffer(buffer *[]byte) {
+	if buffer == nil || cap(*buffer) > maxPoolBufferSize {
+		return
+	}
+	*buffer = (*buffer)[:0]
+	bufferPool.Put(buffer)
+}
@@ -0,0 +1,48 @@
+package golog
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+func TestBufferReset(t *testing.T) {
+	buffer := GetBuffer()
+	*buffer = append(*buffer, "This is one string."...)
+	PutBuffer(buffer)
+
+	for i := 0; i < 10; i++ {
+		buffer := GetBuffer()
+		if len(*buffer) != 0 {
+			t.Fatalf("buffer should be empty, actual: %q", *buffer)
+		}
+		PutBuffer(buffer)
+	}
+}
+
+func TestPutLargeBuffer(t *testing.T) {
+	buffer := make([]byte, 0, maxPoolBufferSize+1)
+	PutBuffer(&buffer)
+	PutBuffer(nil)
+}
+
+func BenchmarkLogSprintf(b *testing.B) {
+	backend := NopBackend{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		backend.Log(Info, []byte(fmt.Sprintf("request %d done\n", i)))
+	}
+}
+
+func BenchmarkLogPoolBuffer(b *testing.B) {
+	backend := NopBackend{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buffer := GetBuffer()
+		*buffer = append(*buffer, "request "...)
+		*buffer = strconv.AppendInt(*buffer, int64(i), 10)
+		*buffer = append(*buffer, " done\n"...)
+		backend.Log(Info, *buffer)
+		PutBuffer(buffer)
+	}
+}
@@ -0,0 +1,78 @@
+package golog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type fileBackendConfig struct {
+	Dir             string `json:"dir"`
+	FlushIntervalMs int    `json:"flushIntervalMs"`
+	RotateByHour    bool   `json:"rotateByHour"`
+	KeepHours       int    `json:"keepHours"`
+	BufferSize      int    `json:"bufferSize"`
+	MinLevel        string `json:"minLevel"`
+}
+
+// NewFileBackendFromConfig creates a FileBackend from a JSON document, like
+//
+//	{"dir": "/var/log/app", "flushIntervalMs": 1000, "rotateByHour": true,
+//	 "keepHours": 24, "bufferSize": 65536, "minLevel": "INFO"}
+//
+// Only dir is required, zero values keep the defaults.
+func NewFileBackendFromConfig(r io.Reader) (*FileBackend, error) {
+	var config fileBackendConfig
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("decode config failed: %w", err)
+	}
+
+	if config.Dir == "" {
+		return nil, fmt.Errorf("dir is required")
+	}
+	if config.FlushIntervalMs < 0 {
+		return nil, fmt.Errorf("invalid flush interval: %vms", config.FlushIntervalMs)
+	}
+	if config.KeepHours < 0 {
+		return nil, fmt.Errorf("invalid keep hours: %v", config.KeepHours)
+	}
+	if config.BufferSize < 0 {
+		return nil, fmt.Errorf("invalid buffer size: %v", config.BufferSize)
+	}
+	minLevel := levelLowest
+	if config.MinLevel != "" {
+		level, err := ParseLevel(config.MinLevel)
+		if err != nil {
+			return nil, err
+		}
+		minLevel = level
+	}
+
+	fileBackend, err := NewFileBackend(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := fileBackend.applyConfig(&config, minLevel); err != nil {
+		fileBackend.Close()
+		return nil, err
+	}
+	return fileBackend, nil
+}
+
+func (s *FileBackend) applyConfig(config *fileBackendConfig, minLevel Level) error {
+	if config.FlushIntervalMs > 0 {
+		s.SetFlushInterval(time.Duration(config.FlushIntervalMs) * time.Millisecond)
+	}
+	if config.BufferSize > 0 {
+		if err := s.SetBufferSize(config.BufferSize); err != nil {
+			return err
+		}
+	}
+	if err := s.SetRotateFileE(config.RotateByHour, config.KeepHours); err != nil {
+		return err
+	}
+	return s.SetMinLevel(minLevel)
+}
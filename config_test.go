@@ -0,0 +1,60 @@
+package golog
+
+import (
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewFileBackendFromConfig(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "fileBackend_test")
+	if err != nil {
+		t.Fatalf("create temporary directoey failed, err: %v", err)
+	}
+	dir := path.Join(tempDir, "log")
+	document := `{"dir": ` + strconv.Quote(dir) + `, "flushIntervalMs": 500,
+		"rotateByHour": true, "keepHours": 24, "bufferSize": 4096, "minLevel": "warning"}`
+	fileBackend, err := NewFileBackendFromConfig(strings.NewReader(document))
+	if err != nil {
+		t.Fatalf("create file backend failed, err: %v", err)
+	}
+	defer fileBackend.Close()
+
+	if fileBackend.dir != dir {
+		t.Errorf("dir not match, expect: %v, actual: %v", dir, fileBackend.dir)
+	}
+	if fileBackend.flushInterval != time.Millisecond*500 {
+		t.Errorf("flush interval not match, actual: %v", fileBackend.flushInterval)
+	}
+	if !fileBackend.rotateByHour || fileBackend.keepHours != 24 {
+		t.Errorf("rotate not match, actual: %v, %v", fileBackend.rotateByHour, fileBackend.keepHours)
+	}
+	if fileBackend.bufferSize != 4096 {
+		t.Errorf("buffer size not match, actual: %v", fileBackend.bufferSize)
+	}
+	if fileBackend.minLevel != Warning {
+		t.Errorf("min level not match, actual: %v", fileBackend.minLevel)
+	}
+}
+
+func TestNewFileBackendFromInvalidConfig(t *testing.T) {
+	documents := []string{
+		`{"dir": "/tmp/log", "unknown": 1}`,
+		`{"flushIntervalMs": 500}`,
+		`{"dir": "/tmp/log", "flushIntervalMs": -1}`,
+		`{"dir": "/tmp/log", "keepHours": -1}`,
+		`{"dir": "/tmp/log", "bufferSize": -1}`,
+		`{"dir": "/tmp/log", "minLevel": "verbose"}`,
+		`{"dir": "/tmp/log", "rotateByHour": "yes"}`,
+		`not json`,
+	}
+	for _, document := range documents {
+		if fileBackend, err := NewFileBackendFromConfig(strings.NewReader(document)); err == nil {
+			fileBackend.Close()
+			t.Errorf("config should be invalid: %s", document)
+		}
+	}
+}
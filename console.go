@@ -0,0 +1,101 @@
+package golog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const colorReset = "\x1b[0m"
+
+var levelColors = map[Level]string{
+	Debug:   "\x1b[90m",
+	Warning: "\x1b[33m",
+	Error:   "\x1b[31m",
+	Fatal:   "\x1b[1;31m",
+}
+
+type ConsoleBackend struct {
+	mutex  sync.Mutex
+	out    io.Writer
+	errOut io.Writer
+	color  bool
+
+	isTerminal func(io.Writer) bool
+}
+
+func NewConsoleBackend() *ConsoleBackend {
+	return NewConsoleBackendWithWriters(os.Stdout, os.Stderr)
+}
+
+func NewConsoleBackendWithWriters(out, errOut io.Writer) *ConsoleBackend {
+	return &ConsoleBackend{
+		out:        out,
+		errOut:     errOut,
+		isTerminal: isTerminal,
+	}
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetColor wraps the content of each level with ANSI color codes, only
+// applied when the output is a terminal.
+func (s *ConsoleBackend) SetColor(color bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.color = color
+}
+
+func colorize(level Level, content []byte) []byte {
+	color, ok := levelColors[level]
+	if !ok {
+		return content
+	}
+	body := bytes.TrimRight(content, "\n")
+	colored := make([]byte, 0, len(content)+len(color)+len(colorReset))
+	colored = append(colored, color...)
+	colored = append(colored, body...)
+	colored = append(colored, colorReset...)
+	return append(colored, content[len(body):]...)
+}
+
+func (s *ConsoleBackend) writerOf(level Level) io.Writer {
+	if !level.below(Warning) {
+		return s.errOut
+	}
+	return s.out
+}
+
+func (s *ConsoleBackend) Log(level Level, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !isValidLevel(level) {
+		fmt.Fprintf(os.Stderr, "invalid level: %v, content: %s", level, content)
+		return
+	}
+	writer := s.writerOf(level)
+	if s.color && s.isTerminal(writer) {
+		content = colorize(level, content)
+	}
+	if _, err := writer.Write(content); err != nil {
+		fmt.Fprintf(os.Stderr, "write console failed: %v", err)
+	}
+}
+
+func (s *ConsoleBackend) Flush() {
+}
+
+func (s *ConsoleBackend) Close() {
+}
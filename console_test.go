@@ -0,0 +1,69 @@
+package golog
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestConsoleBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*ConsoleBackend)(nil)
+}
+
+func TestConsoleBackendRouting(t *testing.T) {
+	var out, errOut bytes.Buffer
+	consoleBackend := NewConsoleBackendWithWriters(&out, &errOut)
+	defer consoleBackend.Close()
+
+	outputContent := map[Level]string{
+		Debug:   "This is a debug string.\n",
+		Info:    "This is a info string.\n",
+		Warning: "This is a warning string.\n",
+		Error:   "This is a error string.\n",
+		Fatal:   "This is a fatal string.\n",
+	}
+	for level := levelMin; level <= levelMax; level++ {
+		consoleBackend.Log(level, []byte(outputContent[level]))
+	}
+	consoleBackend.Flush()
+
+	expectOut := outputContent[Debug] + outputContent[Info]
+	if out.String() != expectOut {
+		t.Errorf("stdout not match, expect: %q, write: %q", expectOut, out.String())
+	}
+	expectErrOut := outputContent[Warning] + outputContent[Error] + outputContent[Fatal]
+	if errOut.String() != expectErrOut {
+		t.Errorf("stderr not match, expect: %q, write: %q", expectErrOut, errOut.String())
+	}
+}
+
+func TestConsoleBackendColor(t *testing.T) {
+	var out, errOut bytes.Buffer
+	consoleBackend := NewConsoleBackendWithWriters(&out, &errOut)
+	defer consoleBackend.Close()
+	consoleBackend.isTerminal = func(io.Writer) bool {
+		return true
+	}
+
+	consoleBackend.SetColor(true)
+	consoleBackend.Log(Error, []byte("colored\n"))
+	consoleBackend.SetColor(false)
+	consoleBackend.Log(Error, []byte("plain\n"))
+
+	expect := "\x1b[31mcolored\x1b[0m\nplain\n"
+	if errOut.String() != expect {
+		t.Errorf("stderr not match, expect: %q, write: %q", expect, errOut.String())
+	}
+}
+
+func TestConsoleBackendColorNotTerminal(t *testing.T) {
+	var out, errOut bytes.Buffer
+	consoleBackend := NewConsoleBackendWithWriters(&out, &errOut)
+	defer consoleBackend.Close()
+
+	consoleBackend.SetColor(true)
+	consoleBackend.Log(Warning, []byte("plain\n"))
+	if expect := "plain\n"; errOut.String() != expect {
+		t.Errorf("stderr not match, expect: %q, write: %q", expect, errOut.String())
+	}
+}
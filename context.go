@@ -0,0 +1,26 @@
+package golog
+
+import "context"
+
+type contextKey int
+
+const (
+	traceIDKey contextKey = iota
+)
+
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey, id)
+}
+
+func TraceIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(traceIDKey).(string)
+	return id, ok
+}
+
+func withTracePrefix(ctx context.Context, content []byte) []byte {
+	id, ok := TraceIDFromContext(ctx)
+	if !ok {
+		return content
+	}
+	return append([]byte("[trace="+id+"] "), content...)
+}
@@ -0,0 +1,29 @@
+package golog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTraceIDFromContext(t *testing.T) {
+	if _, ok := TraceIDFromContext(context.Background()); ok {
+		t.Errorf("background context should not carry trace id")
+	}
+	ctx := WithTraceID(context.Background(), "abc123")
+	id, ok := TraceIDFromContext(ctx)
+	if !ok || id != "abc123" {
+		t.Errorf("trace id not match, expect: abc123, actual: %v", id)
+	}
+}
+
+func TestWithTracePrefix(t *testing.T) {
+	content := "This is one string."
+	if prefixed := withTracePrefix(context.Background(), []byte(content)); string(prefixed) != content {
+		t.Errorf("content without trace id should be unchanged, actual: %s", prefixed)
+	}
+	ctx := WithTraceID(context.Background(), "abc123")
+	expect := "[trace=abc123] " + content
+	if prefixed := withTracePrefix(ctx, []byte(content)); string(prefixed) != expect {
+		t.Errorf("expect: %s, actual: %s", expect, prefixed)
+	}
+}
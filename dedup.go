@@ -0,0 +1,76 @@
+package golog
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+type dedupState struct {
+	last  []byte
+	count int
+}
+
+// DedupBackend collapses identical consecutive entries of each level into
+// one, followed by a "(repeated N times)" line when the entry changes or on
+// flush.
+type DedupBackend struct {
+	mutex   sync.Mutex
+	backend Backend
+	states  map[Level]*dedupState
+}
+
+func NewDedupBackend(b Backend) *DedupBackend {
+	return &DedupBackend{
+		backend: b,
+		states:  make(map[Level]*dedupState),
+	}
+}
+
+func (s *DedupBackend) Log(level Level, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	state, ok := s.states[level]
+	if !ok {
+		state = &dedupState{}
+		s.states[level] = state
+	}
+	if state.count > 0 && bytes.Equal(state.last, content) {
+		state.count++
+		return
+	}
+	s.writeRepeated(level, state)
+	state.last = append(state.last[:0], content...)
+	state.count = 1
+	s.backend.Log(level, content)
+}
+
+func (s *DedupBackend) writeRepeated(level Level, state *dedupState) {
+	if state.count > 1 {
+		s.backend.Log(level, []byte(fmt.Sprintf("(repeated %d times)\n", state.count)))
+	}
+	state.count = 0
+}
+
+// writeAllRepeated ends the repeats of all levels, the mutex must be held.
+func (s *DedupBackend) writeAllRepeated() {
+	for _, level := range levels() {
+		if state, ok := s.states[level]; ok {
+			s.writeRepeated(level, state)
+		}
+	}
+}
+
+func (s *DedupBackend) Flush() {
+	s.mutex.Lock()
+	s.writeAllRepeated()
+	s.mutex.Unlock()
+	s.backend.Flush()
+}
+
+func (s *DedupBackend) Close() {
+	s.mutex.Lock()
+	s.writeAllRepeated()
+	s.mutex.Unlock()
+	s.backend.Close()
+}
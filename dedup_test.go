@@ -0,0 +1,41 @@
+package golog
+
+import (
+	"testing"
+)
+
+func TestDedupBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*DedupBackend)(nil)
+}
+
+func TestDedupBackend(t *testing.T) {
+	backend := NewMemoryBackend()
+	dedupBackend := NewDedupBackend(backend)
+	for i := 0; i < 5; i++ {
+		dedupBackend.Log(Error, []byte("retry failed\n"))
+	}
+	dedupBackend.Log(Info, []byte("info\n"))
+	dedupBackend.Log(Error, []byte("given up\n"))
+	dedupBackend.Log(Error, []byte("given up\n"))
+	dedupBackend.Flush()
+	dedupBackend.Log(Error, []byte("given up\n"))
+
+	expects := []Entry{
+		{Error, []byte("retry failed\n")},
+		{Info, []byte("info\n")},
+		{Error, []byte("(repeated 5 times)\n")},
+		{Error, []byte("given up\n")},
+		{Error, []byte("(repeated 2 times)\n")},
+		{Error, []byte("given up\n")},
+	}
+	entries := backend.Entries()
+	if len(entries) != len(expects) {
+		t.Fatalf("entries count not match, expect: %v, actual: %v", len(expects), len(entries))
+	}
+	for i, expect := range expects {
+		if entries[i].Level != expect.Level || string(entries[i].Content) != string(expect.Content) {
+			t.Errorf("entry not match, expect: %v %s, actual: %v %s",
+				expect.Level, expect.Content, entries[i].Level, entries[i].Content)
+		}
+	}
+}
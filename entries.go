@@ -0,0 +1,209 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadEntries flushes level and returns the lines of its rotated and current
+// files logged from from until to, both inclusive, oldest first. The rotated
+// files include the ones rotated by size or lines. It needs the
+// timestamp prefix enabled with a layout including the date. Lines without a
+// timestamp are appended to the entry before them, e.g. the lines of an error
+// chain.
+func (s *FileBackend) ReadEntries(level Level, from, to time.Time) ([]Entry, error) {
+	if !isValidLevel(level) {
+		return nil, fmt.Errorf("invalid level: %v", level)
+	}
+	s.mutex.Lock()
+	layout := s.timestampLayout
+	location := s.now().Location()
+	includeSequence := s.includeSequence
+	// the level name is prefixed only in files shared by levels.
+	levelPrefix := ""
+	if s.levelFileName(level) != levelNames[level] && s.formatter == nil {
+		levelPrefix = levelNames[level]
+	}
+	currentPath := ""
+	if writer := s.writer[level]; writer != nil {
+		if err := writer.flush(); err != nil {
+			s.mutex.Unlock()
+			return nil, fmt.Errorf("flush %s failed: %w", writer.filePath, err)
+		}
+		currentPath = writer.filePath
+	}
+	s.mutex.Unlock()
+	if layout == "" {
+		return nil, fmt.Errorf("timestamp prefix is not enabled")
+	}
+
+	paths, err := s.rotatedFilesOf(level)
+	if err != nil {
+		return nil, err
+	}
+	if currentPath != "" {
+		paths = append(paths, currentPath)
+	}
+	parser := entryParser{
+		layout:          layout,
+		location:        location,
+		includeSequence: includeSequence,
+		levelPrefix:     levelPrefix,
+	}
+	var entries []Entry
+	for _, filePath := range paths {
+		fileEntries, err := parser.readFile(level, filePath, from, to)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+// rotatedFilesOf returns the paths of the files rotated by time, size or
+// lines of level, oldest first.
+func (s *FileBackend) rotatedFilesOf(level Level) ([]string, error) {
+	s.mutex.Lock()
+	name := s.levelFileName(level)
+	timePattern := s.rotatedFilenamePattern
+	indexPattern := s.indexedFilenamePattern
+	s.mutex.Unlock()
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var rotatedFiles []os.FileInfo
+	for _, file := range files {
+		matches := timePattern.FindStringSubmatch(file.Name())
+		if matches == nil {
+			matches = indexPattern.FindStringSubmatch(file.Name())
+		}
+		if matches == nil || matches[1] != name {
+			continue
+		}
+		rotatedFiles = append(rotatedFiles, file)
+	}
+	sort.SliceStable(rotatedFiles, func(i, j int) bool {
+		return rotatedBefore(timePattern, indexPattern, rotatedFiles[i], rotatedFiles[j])
+	})
+	paths := make([]string, 0, len(rotatedFiles)+1)
+	for _, file := range rotatedFiles {
+		paths = append(paths, path.Join(s.dir, file.Name()))
+	}
+	return paths, nil
+}
+
+type entryParser struct {
+	layout          string
+	location        *time.Location
+	includeSequence bool
+	levelPrefix     string
+}
+
+// timeOf parses the time of line, ok is false if line does not start with
+// the prefixes. Lines of other levels sharing the file have ok but not
+// matched set.
+func (p *entryParser) timeOf(line string) (t time.Time, matched bool, ok bool) {
+	if p.includeSequence {
+		index := strings.IndexByte(line, ' ')
+		if index < 0 {
+			return time.Time{}, false, false
+		}
+		if _, err := strconv.ParseUint(line[:index], 10, 64); err != nil {
+			return time.Time{}, false, false
+		}
+		line = line[index+1:]
+	}
+	t, rest, ok := p.parseTimestamp(line)
+	if !ok {
+		return time.Time{}, false, false
+	}
+	if p.levelPrefix != "" && !strings.HasPrefix(rest, p.levelPrefix+" ") {
+		return t, false, true
+	}
+	return t, true, true
+}
+
+// parseTimestamp parses the timestamp prefix of line followed by a space,
+// returning the rest of line. The timestamp has as many spaces as the layout,
+// plus one for each padding underscore, e.g. "Jan _2" on the 2nd.
+func (p *entryParser) parseTimestamp(line string) (time.Time, string, bool) {
+	minSpaces := strings.Count(p.layout, " ")
+	maxSpaces := minSpaces + strings.Count(p.layout, "_")
+	spaces := 0
+	for i := 0; i < len(line) && spaces <= maxSpaces; i++ {
+		if line[i] != ' ' {
+			continue
+		}
+		if spaces >= minSpaces {
+			if t, err := time.ParseInLocation(p.layout, line[:i], p.location); err == nil {
+				return t, line[i+1:], true
+			}
+		}
+		spaces++
+	}
+	return time.Time{}, "", false
+}
+
+func (p *entryParser) readFile(level Level, filePath string, from, to time.Time) ([]Entry, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if strings.HasSuffix(filePath, gzipFileSuffix) {
+		gzipReader, err := gzip.NewReader(file)
+		if err == io.EOF {
+			// the stream of the current file may have nothing flushed.
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s failed: %w", filePath, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	var entries []Entry
+	// appending is set while the lines belong to an entry in the range.
+	appending := false
+	bufferedReader := bufio.NewReader(reader)
+	for {
+		line, err := bufferedReader.ReadBytes('\n')
+		if len(line) > 0 {
+			t, matched, ok := p.timeOf(string(line))
+			switch {
+			case !ok:
+				if appending {
+					last := &entries[len(entries)-1]
+					last.Content = append(last.Content, line...)
+				}
+			case matched && !t.Before(from) && !t.After(to):
+				entries = append(entries, Entry{Level: level, Content: bytes.Clone(line)})
+				appending = true
+			default:
+				appending = false
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// the stream of the current compressed file is not closed.
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s failed: %w", filePath, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package golog
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReadEntries(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	nowTime := time.Date(2020, 1, 2, 3, 0, 0, 0, time.Local)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	if _, err := fileBackend.ReadEntries(Info, nowTime, nowTime); err == nil {
+		t.Errorf("read entries without timestamp should fail")
+	}
+	fileBackend.SetTimestampLayout("2006-01-02 15:04:05")
+	fileBackend.SetCompressRotated(true)
+
+	fileBackend.Log(Info, []byte("first\n"))
+	if err := fileBackend.RotateNow(); err != nil {
+		t.Fatalf("rotate failed, err: %v", err)
+	}
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.Log(Info, []byte("second\n  detail\n"))
+	if err := fileBackend.RotateNow(); err != nil {
+		t.Fatalf("rotate failed, err: %v", err)
+	}
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.Log(Info, []byte("third\n"))
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.Log(Info, []byte("fourth\n"))
+
+	from := time.Date(2020, 1, 2, 4, 0, 0, 0, time.Local)
+	to := time.Date(2020, 1, 2, 5, 0, 0, 0, time.Local)
+	entries, err := fileBackend.ReadEntries(Info, from, to)
+	if err != nil {
+		t.Fatalf("read entries failed, err: %v", err)
+	}
+	expects := []string{
+		"2020-01-02 04:00:00 second\n  detail\n",
+		"2020-01-02 05:00:00 third\n",
+	}
+	if len(entries) != len(expects) {
+		t.Fatalf("entry count not match, expect: %d, actual: %d", len(expects), len(entries))
+	}
+	for i, expect := range expects {
+		if entries[i].Level != Info || string(entries[i].Content) != expect {
+			t.Errorf("entry not match, expect: %q, actual: %v %q",
+				expect, entries[i].Level, entries[i].Content)
+		}
+	}
+}
+
+func TestReadEntriesRotatedByLines(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	nowTime := time.Date(2020, 1, 2, 3, 0, 0, 0, time.Local)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.SetTimestampLayout("2006-01-02 15:04:05")
+	fileBackend.SetRotateByLines(2)
+
+	// INFO.log.1, INFO.log.2 and the current file.
+	for i := 0; i < 5; i++ {
+		fileBackend.Log(Info, []byte(strconv.Itoa(i)+"\n"))
+		nowTime = nowTime.Add(time.Minute)
+	}
+
+	from := time.Date(2020, 1, 2, 3, 1, 0, 0, time.Local)
+	to := time.Date(2020, 1, 2, 3, 4, 0, 0, time.Local)
+	entries, err := fileBackend.ReadEntries(Info, from, to)
+	if err != nil {
+		t.Fatalf("read entries failed, err: %v", err)
+	}
+	expects := []string{
+		"2020-01-02 03:01:00 1\n",
+		"2020-01-02 03:02:00 2\n",
+		"2020-01-02 03:03:00 3\n",
+		"2020-01-02 03:04:00 4\n",
+	}
+	if len(entries) != len(expects) {
+		t.Fatalf("entry count not match, expect: %d, actual: %d", len(expects), len(entries))
+	}
+	for i, expect := range expects {
+		if string(entries[i].Content) != expect {
+			t.Errorf("entry not match, expect: %q, actual: %q", expect, entries[i].Content)
+		}
+	}
+}
+
+func TestReadEntriesPaddedLayout(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	nowTime := time.Date(2020, 1, 2, 3, 0, 0, 0, time.Local)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.SetTimestampLayout(time.ANSIC)
+	fileBackend.Log(Info, []byte("second day\n"))
+	nowTime = nowTime.AddDate(0, 0, 10)
+	fileBackend.Log(Info, []byte("twelfth day\n"))
+
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	entries, err := fileBackend.ReadEntries(Info, from, nowTime)
+	if err != nil {
+		t.Fatalf("read entries failed, err: %v", err)
+	}
+	expects := []string{
+		nowTime.AddDate(0, 0, -10).Format(time.ANSIC) + " second day\n",
+		nowTime.Format(time.ANSIC) + " twelfth day\n",
+	}
+	if len(entries) != len(expects) {
+		t.Fatalf("entry count not match, expect: %d, actual: %d", len(expects), len(entries))
+	}
+	for i, expect := range expects {
+		if string(entries[i].Content) != expect {
+			t.Errorf("entry not match, expect: %q, actual: %q", expect, entries[i].Content)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package golog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// StackTracer is implemented by errors carrying the frames of the stack
+// where they were created.
+type StackTracer interface {
+	Frames() []runtime.Frame
+}
+
+// formatErrorChain renders err followed by each error of its Unwrap chain,
+// with the frames of the errors implementing StackTracer. Wrappers adding
+// neither message nor frames are skipped.
+func formatErrorChain(err error) []byte {
+	var builder strings.Builder
+	builder.WriteString(err.Error())
+	builder.WriteByte('\n')
+	writeFrames(&builder, err)
+	message := err.Error()
+	for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+		_, traced := cause.(StackTracer)
+		if cause.Error() == message && !traced {
+			continue
+		}
+		message = cause.Error()
+		builder.WriteString("  caused by: ")
+		builder.WriteString(message)
+		builder.WriteByte('\n')
+		writeFrames(&builder, cause)
+	}
+	return []byte(builder.String())
+}
+
+func writeFrames(builder *strings.Builder, err error) {
+	tracer, ok := err.(StackTracer)
+	if !ok {
+		return
+	}
+	for _, frame := range tracer.Frames() {
+		fmt.Fprintf(builder, "    at %s (%s:%d)\n", frame.Function, frame.File, frame.Line)
+	}
+}
+
+// LogError writes err and the chain of errors it wraps, nil is ignored.
+func (s *FileBackend) LogError(level Level, err error) {
+	if err == nil {
+		return
+	}
+	content := formatErrorChain(err)
+	if _, err := s.logDepth(1, level, content); err != nil {
+		fmt.Fprintf(os.Stderr, "%v, content: %s", err, content)
+	}
+}
+
+// LogError writes err and the chain of errors it wraps, nil is ignored.
+func (s *Logger) LogError(level Level, err error) {
+	if err == nil || !s.IsLevelEnabled(level) {
+		return
+	}
+	s.Log(level, formatErrorChain(err))
+}
@@ -0,0 +1,71 @@
+package golog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"runtime"
+	"testing"
+)
+
+type tracedError struct {
+	err    error
+	frames []runtime.Frame
+}
+
+func (e *tracedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *tracedError) Unwrap() error {
+	return e.err
+}
+
+func (e *tracedError) Frames() []runtime.Frame {
+	return e.frames
+}
+
+func TestFormatErrorChain(t *testing.T) {
+	root := errors.New("no such file")
+	traced := &tracedError{
+		err: fmt.Errorf("open config: %w", root),
+		frames: []runtime.Frame{
+			{Function: "main.load", File: "main.go", Line: 12},
+			{Function: "main.main", File: "main.go", Line: 3},
+		},
+	}
+	err := fmt.Errorf("start failed: %w", traced)
+
+	expect := "start failed: open config: no such file\n" +
+		"  caused by: open config: no such file\n" +
+		"    at main.load (main.go:12)\n" +
+		"    at main.main (main.go:3)\n" +
+		"  caused by: no such file\n"
+	if actual := string(formatErrorChain(err)); actual != expect {
+		t.Errorf("error chain not match, expect: %q, actual: %q", expect, actual)
+	}
+}
+
+func TestLogError(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.LogError(Error, nil)
+	fileBackend.LogError(Error, fmt.Errorf("query failed: %w", errors.New("timeout")))
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Error]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if expect := "query failed: timeout\n  caused by: timeout\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+
+	backend := NewMemoryBackend()
+	NewLogger(backend).LogError(Warning, errors.New("plain"))
+	entries := backend.Entries()
+	if len(entries) != 1 || entries[0].Level != Warning || string(entries[0].Content) != "plain\n" {
+		t.Errorf("logger entries not match, actual: %v", entries)
+	}
+}
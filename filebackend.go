@@ -2,271 +2,2298 @@ package golog
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 const (
-	defaultFlushInterval = time.Second * 3
-	defaultBufferSize    = 256 * 1024
-	datetimeSuffixLayout = "2006010215"
-	logFileSuffix        = ".log"
+	defaultFlushInterval       = time.Second * 3
+	defaultMonitorInterval     = time.Second * 5
+	defaultRotateCheckInterval = time.Second
+	defaultBufferSize          = 256 * 1024
+	datetimeSuffixLayout       = "2006010215"
+	dailySuffixLayout          = "20060102"
+	logFileSuffix              = ".log"
+	gzipFileSuffix             = ".gz"
+	combinedFileName           = "combined"
+	defaultFileMode            = os.FileMode(0644)
+	defaultDirMode             = os.FileMode(0755)
+	diskFullRetryInterval      = time.Second * 10
+	openRetryInterval          = time.Second * 10
+	truncatedMarker            = "...[truncated]"
+	defaultPauseCapacity       = 1024
+)
+
+var errWritesPaused = errors.New("writes paused since disk is full")
+
+type RotateInterval int
+
+const (
+	RotateHourly RotateInterval = iota
+	RotateDaily
 )
 
 var (
-	rotatedFilenamePattern *regexp.Regexp
+	rotatedFilenamePattern      *regexp.Regexp
+	dailyRotatedFilenamePattern *regexp.Regexp
+	indexedFilenamePattern      *regexp.Regexp
 )
 
 func init() {
-	names := make([]string, 0, len(levelNames))
+	updateRotatedFilenamePatterns()
+}
+
+func updateRotatedFilenamePatterns() {
+	rotatedFilenamePattern = newRotatedFilenamePattern("", datetimeSuffixLayout)
+	dailyRotatedFilenamePattern = newRotatedFilenamePattern("", dailySuffixLayout)
+	indexedFilenamePattern = newIndexedFilenamePattern("")
+}
+
+// filenamesPattern matches the names of the current files, without the
+// suffix.
+func filenamesPattern(prefix string, extraNames []string) string {
+	if prefix != "" {
+		prefix = regexp.QuoteMeta(prefix + ".")
+	}
+	names := make([]string, 0, len(levelNames)+len(extraNames)+1)
+	names = append(names, combinedFileName)
 	for _, name := range levelNames {
-		names = append(names, name)
+		names = append(names, regexp.QuoteMeta(name))
 	}
-	rotatedFilenamePattern = regexp.MustCompile(fmt.Sprintf(
-		"(%s)\\.log\\.20[0-9]{8}", strings.Join(names, "|")))
+	for _, name := range extraNames {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	return fmt.Sprintf("^%s(%s)", prefix, strings.Join(names, "|"))
+}
+
+func newRotatedFilenamePattern(prefix string, suffixLayout string, extraNames ...string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(
+		"%s\\.log\\.(?P<time>20[0-9]{%d})(-[0-9]+)?(\\.gz)?$",
+		filenamesPattern(prefix, extraNames), len(suffixLayout)-2))
+}
+
+// newIndexedFilenamePattern matches the names of the files rotated by size or
+// lines, which may match the time rotated pattern too.
+func newIndexedFilenamePattern(prefix string, extraNames ...string) *regexp.Regexp {
+	return regexp.MustCompile(filenamesPattern(prefix, extraNames) +
+		"\\.log\\.(?P<index>[0-9]+)(\\.gz)?$")
 }
 
 func truncateToHour(t time.Time) time.Time {
-	return t.Truncate(time.Hour)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 }
 
 type syncBufio struct {
-	writer    *bufio.Writer
-	file      *os.File
-	writeSize uint64
-	filePath  string
+	writer *bufio.Writer
+	// gzipWriter is between writer and file if the file is compressed.
+	gzipWriter *gzip.Writer
+	file       *os.File
+	writeSize  uint64
+	// writeLines counts the writes since the file is opened.
+	writeLines uint64
+	filePath   string
+	// createdEmpty is set if the file was empty when opened.
+	createdEmpty bool
+	// output is what writer flushes into, wrapping gzipWriter or file.
+	output io.Writer
+	// firstWriteTime is the time of the first log written since opened.
+	firstWriteTime time.Time
+	// slowIO is called with the operations exceeding the threshold.
+	slowIO func(op string, d time.Duration)
+	// dirty is set by write and cleared once the buffer is flushed, then
+	// unsynced is set until the file is synced.
+	dirty         bool
+	unsynced      bool
+	lastFlushTime time.Time
+	syncFile      func(*os.File) error
 }
 
-func newSyncBufio(file *os.File, filepath string, bufferSize int) *syncBufio {
-	return &syncBufio{
-		writer:   bufio.NewWriterSize(file, bufferSize),
+// newSyncBufio creates the writer of file, content is compressed if the
+// name of the file ends with .gz. The output is wrapped by wrap if not nil.
+func newSyncBufio(file *os.File, filepath string, bufferSize int,
+	wrap func(io.Writer) io.Writer) *syncBufio {
+	writer := &syncBufio{
 		file:     file,
 		filePath: filepath,
+		syncFile: (*os.File).Sync,
+
+		lastFlushTime: time.Now(),
+	}
+	if strings.HasSuffix(filepath, gzipFileSuffix) {
+		writer.gzipWriter = gzip.NewWriter(file)
+	}
+	writer.output = writer.target()
+	if wrap != nil {
+		writer.output = wrap(writer.output)
+	}
+	writer.writer = bufio.NewWriterSize(writer.output, bufferSize)
+	return writer
+}
+
+func (s *syncBufio) target() io.Writer {
+	if s.gzipWriter != nil {
+		return s.gzipWriter
+	}
+	return s.file
+}
+
+// timed reports the duration of op started at start to slowIO.
+func (s *syncBufio) timed(op string, start time.Time) {
+	if s.slowIO != nil {
+		s.slowIO(op, time.Since(start))
 	}
 }
 
 func (s *syncBufio) flush() error {
-	return s.writer.Flush()
+	defer s.timed("flush", time.Now())
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if s.gzipWriter != nil && s.dirty {
+		if err := s.gzipWriter.Flush(); err != nil {
+			return err
+		}
+	}
+	if s.dirty {
+		s.dirty = false
+		s.unsynced = true
+	}
+	return nil
+}
+
+func (s *syncBufio) resize(bufferSize int) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	s.writer = bufio.NewWriterSize(s.output, bufferSize)
+	return nil
 }
 
 func (s *syncBufio) sync() error {
-	return s.file.Sync()
+	if !s.unsynced {
+		return nil
+	}
+	defer s.timed("sync", time.Now())
+	if err := s.syncFile(s.file); err != nil {
+		return err
+	}
+	s.unsynced = false
+	return nil
 }
 
-func (s *syncBufio) close() error {
+// flushAndSync flushes and syncs pending content, does nothing when nothing
+// was written since last time.
+func (s *syncBufio) flushAndSync() error {
+	if !s.dirty && !s.unsynced {
+		return nil
+	}
 	if err := s.flush(); err != nil {
-		fmt.Fprintf(os.Stderr, "flush failed: %v", err)
+		return fmt.Errorf("flush %s failed: %w", s.filePath, err)
 	}
 	if err := s.sync(); err != nil {
-		fmt.Fprintf(os.Stderr, "sync failed: %v", err)
+		return fmt.Errorf("sync %s failed: %w", s.filePath, err)
+	}
+	s.lastFlushTime = time.Now()
+	return nil
+}
+
+func (s *syncBufio) close() error {
+	if err := s.flushAndSync(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v", err)
+	}
+	if s.gzipWriter != nil {
+		if err := s.gzipWriter.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close gzip stream of %s failed: %v", s.filePath, err)
+		}
 	}
 	return s.file.Close()
 }
 
-func (s *syncBufio) write(content []byte) {
+// write counts the bytes accepted into writeSize even if it fails, but only
+// counts a line once the whole content is accepted.
+func (s *syncBufio) write(content []byte) (int, error) {
 	writeCount, err := s.writer.Write(content)
+	s.writeSize += uint64(writeCount)
+	s.dirty = true
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "write file failed: %v", err)
+		return writeCount, fmt.Errorf("write %s failed: %w", s.filePath, err)
 	}
-	s.writeSize += uint64(writeCount)
+	s.writeLines++
+	return writeCount, nil
+}
+
+type BackendStats struct {
+	Lines   uint64
+	Bytes   uint64
+	Dropped uint64
+}
+
+type levelStats struct {
+	lines   uint64
+	bytes   uint64
+	dropped uint64
 }
 
 type FileBackend struct {
-	mutex          sync.Mutex
-	dir            string
-	writer         [levelCount]*syncBufio
-	flushInterval  time.Duration
-	rotateByHour   bool
-	lastRotateTime int64
-	keepHours      int
+	mutex              sync.Mutex
+	dir                string
+	writer             map[Level]*syncBufio
+	flushInterval      time.Duration
+	levelFlushInterval map[Level]time.Duration
+	rotateByHour       bool
+	rotateInterval     RotateInterval
+	lastRotateTime     int64
+	keepHours          int
+	rotateSize         uint64
+	rotateLines        uint64
+	fileMode           os.FileMode
+	dirMode            os.FileMode
+	compressRotated    bool
+	bufferSize         int
+	formatter          Formatter
+	timestampLayout    string
+	minLevel           Level
+	disabledLevels     map[Level]bool
+	disabledMask       uint32
+	includeCaller      bool
+	callerSkip         int
+	includeSequence    bool
+	ensureNewline      bool
+	maxLineBytes       int
+	fileHeader         func(level Level) []byte
+	streamCompression  bool
+	exclusiveLock      bool
+	lazyFileCreation   bool
+	truncateOnOpen     bool
+	writtenPaths       map[string]bool
+	onRotate           func(level Level, rotatedPath string)
+	sequence           uint64
+	combinedFile       bool
+	levelRouting       map[Level]string
+	filePrefix         string
+	stats              sync.Map
+	rotations          uint64
+	syncEveryWrite     bool
+	maxTotalBytes      uint64
+	done               chan struct{}
+	loops              sync.WaitGroup
+	onWriteError       func(Level, error)
+	retentionPolicy    RetentionPolicy
+	fallbackWriter     io.Writer
+	openRetryTime      map[Level]time.Time
+	writerWrapper      func(level Level, w io.Writer) io.Writer
+	exitOnFatal        bool
+	binarySafe         bool
+	encoder            Encoder
+	suffixByFirstWrite bool
+	flushOnLevel       Level
+	slowIOThreshold    time.Duration
+	onSlowIO           func(op string, d time.Duration)
+	mirror             *syncBufio
+	mirrorLevel        Level
+	writePausedUntil   time.Time
+	paused             bool
+	pauseDrop          bool
+	pauseCapacity      int
+	heldEntries        []heldEntry
+	followers          []*follower
+
+	monitorInterval     time.Duration
+	rotateCheckInterval time.Duration
+
+	flushIntervalChanged       chan struct{}
+	monitorIntervalChanged     chan struct{}
+	rotateCheckIntervalChanged chan struct{}
 
 	rotatedFilenamePattern *regexp.Regexp
+	indexedFilenamePattern *regexp.Regexp
+	customRotatedPattern   *regexp.Regexp
+	rotateNameFunc         func(originalPath string, t time.Time) string
 	getNowTime             func() time.Time
+	location               *time.Location
+	exit                   func(code int)
 }
 
 func NewFileBackend(dir string) (*FileBackend, error) {
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := os.MkdirAll(dir, defaultDirMode); err != nil {
 		return nil, err
 	}
 	var fileBackend FileBackend
 	fileBackend.dir = dir
+	fileBackend.writer = make(map[Level]*syncBufio)
+	fileBackend.minLevel = levelLowest
+	fileBackend.flushOnLevel = Fatal
+	fileBackend.pauseCapacity = defaultPauseCapacity
+	fileBackend.fileMode = defaultFileMode
+	fileBackend.dirMode = defaultDirMode
+	fileBackend.bufferSize = defaultBufferSize
 	fileBackend.flushInterval = defaultFlushInterval
+	fileBackend.monitorInterval = defaultMonitorInterval
+	fileBackend.rotateCheckInterval = defaultRotateCheckInterval
+	fileBackend.levelFlushInterval = make(map[Level]time.Duration)
+	fileBackend.disabledLevels = make(map[Level]bool)
 	fileBackend.rotatedFilenamePattern = rotatedFilenamePattern
+	fileBackend.indexedFilenamePattern = indexedFilenamePattern
 	fileBackend.getNowTime = time.Now
+	fileBackend.exit = os.Exit
+	fileBackend.fallbackWriter = os.Stderr
+	fileBackend.openRetryTime = make(map[Level]time.Time)
+	fileBackend.writtenPaths = make(map[string]bool)
 
-	for i := levelMin; i <= levelMax; i++ {
-		filepath := path.Join(dir, levelNames[i]+logFileSuffix)
-		if err := fileBackend.openSyncBufio(i, filepath); err != nil {
-			return nil, err
+	for _, i := range levels() {
+		if err := fileBackend.openLevel(i); err != nil {
+			fmt.Fprintf(os.Stderr, "open %s failed, fall back: %v", levelNames[i], err)
+			fileBackend.openRetryTime[i] = fileBackend.now().Add(openRetryInterval)
 		}
 	}
 
-	intervalLoop := func(f func(), d time.Duration) {
-		for {
-			time.Sleep(d)
+	fileBackend.done = make(chan struct{})
+	fileBackend.flushIntervalChanged = make(chan struct{}, 1)
+	fileBackend.monitorIntervalChanged = make(chan struct{}, 1)
+	fileBackend.rotateCheckIntervalChanged = make(chan struct{}, 1)
+
+	fileBackend.loops.Add(3)
+	go fileBackend.intervalLoop(fileBackend.doFlush,
+		fileBackend.getFlushInterval, fileBackend.flushIntervalChanged)
+	go fileBackend.intervalLoop(fileBackend.doMonitorFiles,
+		fileBackend.getMonitorInterval, fileBackend.monitorIntervalChanged)
+	go fileBackend.intervalLoop(fileBackend.doRotateByHour,
+		fileBackend.getRotateCheckInterval, fileBackend.rotateCheckIntervalChanged)
+
+	return &fileBackend, nil
+}
+
+// intervalLoop calls f every interval until the backend is closed, a
+// non-positive interval pauses the loop until reset.
+func (s *FileBackend) intervalLoop(f func(), interval func() time.Duration, reset <-chan struct{}) {
+	defer s.loops.Done()
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		var tick <-chan time.Time
+		if d := interval(); d > 0 {
+			timer.Reset(d)
+			tick = timer.C
+		}
+		select {
+		case <-s.done:
+			return
+		case <-reset:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-tick:
 			f()
 		}
 	}
+}
+
+// levelFileName returns the name of the file level is written into, without
+// the prefix and the suffix.
+func (s *FileBackend) levelFileName(level Level) string {
+	if name, ok := s.levelRouting[level]; ok {
+		return name
+	}
+	if s.combinedFile {
+		return combinedFileName
+	}
+	return levelNames[level]
+}
 
-	go intervalLoop((&fileBackend).Flush, fileBackend.flushInterval)
-	go intervalLoop((&fileBackend).doMonitorFiles, time.Second*5)
-	go intervalLoop((&fileBackend).doRotateByHour, time.Second*1)
+func (s *FileBackend) levelFilePath(level Level) string {
+	name := s.levelFileName(level)
+	if s.filePrefix != "" {
+		name = s.filePrefix + "." + name
+	}
+	if s.streamCompression {
+		return path.Join(s.dir, name+logFileSuffix+gzipFileSuffix)
+	}
+	return path.Join(s.dir, name+logFileSuffix)
+}
 
-	return &fileBackend, nil
+// reopenLevels closes all writers and opens them again, used when the file
+// names of levels change.
+func (s *FileBackend) reopenLevels() error {
+	s.close()
+	if s.lazyFileCreation {
+		return nil
+	}
+	for _, i := range levels() {
+		if i.below(s.minLevel) {
+			continue
+		}
+		if err := s.openLevel(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openSyncBufio opens the file of level, the header is written if the file
+// is empty. The file is truncated after being locked if truncate is set.
+func (s *FileBackend) openSyncBufio(filepath string, level Level, truncate bool) (*syncBufio, error) {
+	// nothing closes the files opened after Close.
+	if s.isClosed() {
+		return nil, fmt.Errorf("backend is closed")
+	}
+	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, s.fileMode)
+	if err != nil {
+		return nil, err
+	}
+	if s.exclusiveLock {
+		if err := lockFile(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("lock %s failed: %w", filepath, err)
+		}
+	}
+	if truncate {
+		if err := file.Truncate(0); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	var wrap func(io.Writer) io.Writer
+	if wrapper := s.writerWrapper; wrapper != nil {
+		wrap = func(w io.Writer) io.Writer {
+			return wrapper(level, w)
+		}
+	}
+	writer := newSyncBufio(file, filepath, s.bufferSize, wrap)
+	writer.slowIO = s.slowIO()
+	if info, err := file.Stat(); err == nil {
+		writer.writeSize = uint64(info.Size())
+		writer.createdEmpty = info.Size() == 0
+	}
+	if s.fileHeader != nil && writer.writeSize == 0 {
+		if _, err := writer.write(s.fileHeader(level)); err != nil {
+			file.Close()
+			return nil, err
+		}
+		// the header is not a line of log.
+		writer.writeLines = 0
+	}
+	s.notifyFollowers(filepath)
+	return writer, nil
 }
 
-func (s *FileBackend) openSyncBufio(level Level, filepath string) error {
-	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// openLevel opens the writer of level, levels written into the same file
+// share one writer.
+func (s *FileBackend) openLevel(level Level) error {
+	filepath := s.levelFilePath(level)
+	for _, writer := range s.writer {
+		if writer != nil && writer.filePath == filepath {
+			s.writer[level] = writer
+			return nil
+		}
+	}
+	writer, err := s.openSyncBufio(filepath, level, s.truncateOnOpen && !s.writtenPaths[filepath])
 	if err != nil {
 		return err
 	}
-	s.writer[level] = newSyncBufio(file, filepath, defaultBufferSize)
+	s.writer[level] = writer
 	return nil
 }
 
+// closeLevel detaches the writer of level, and closes it if no other level
+// shares it.
+func (s *FileBackend) closeLevel(level Level) {
+	writer := s.writer[level]
+	s.writer[level] = nil
+	for _, other := range s.writer {
+		if other == writer {
+			return
+		}
+	}
+	if err := writer.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "close failed: %v", err)
+	}
+}
+
+// writers returns the distinct opened writers in level order.
+func (s *FileBackend) writers() []*syncBufio {
+	var result []*syncBufio
+	seen := make(map[*syncBufio]bool)
+	for _, i := range levels() {
+		writer := s.writer[i]
+		if writer == nil || seen[writer] {
+			continue
+		}
+		seen[writer] = true
+		result = append(result, writer)
+	}
+	return result
+}
+
+// SetRotateFile is like SetRotateFileE but only reports invalid arguments to
+// stderr.
+//
+// Deprecated: use SetRotateFileE instead.
 func (s *FileBackend) SetRotateFile(rotateByHour bool, keepHours int) {
+	if err := s.SetRotateFileE(rotateByHour, keepHours); err != nil {
+		fmt.Fprintf(os.Stderr, "set rotate file failed: %v", err)
+	}
+}
+
+// SetRotateFileE enables rotating the files by time, rotated files older than
+// keepHours are deleted, 0 keeps them forever.
+func (s *FileBackend) SetRotateFileE(rotateByHour bool, keepHours int) error {
+	if rotateByHour && keepHours < 0 {
+		return fmt.Errorf("invalid keep hours: %v", keepHours)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	s.rotateByHour = rotateByHour
 	if rotateByHour {
 		s.keepHours = keepHours
-		s.lastRotateTime = truncateToHour(s.getNowTime()).Unix()
+		s.lastRotateTime = s.truncateTime(s.now()).Unix()
 	} else {
 		s.lastRotateTime = 0
 	}
+	return nil
 }
 
-func (s *FileBackend) SetFlushInterval(t time.Duration) {
-	s.flushInterval = t
+// SetKeepHours changes how long rotated files are kept without touching the
+// rotation state, 0 keeps them forever.
+func (s *FileBackend) SetKeepHours(keepHours int) error {
+	if keepHours < 0 {
+		return fmt.Errorf("invalid keep hours: %v", keepHours)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keepHours = keepHours
+	return nil
 }
 
-func (s *FileBackend) doRotateByHour() {
-	if !s.rotateByHour {
-		return
+func (s *FileBackend) KeepHours() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.keepHours
+}
+
+func (s *FileBackend) SetRotateInterval(interval RotateInterval) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rotateInterval = interval
+	s.updateRotatedFilenamePattern()
+	if s.rotateByHour {
+		s.lastRotateTime = s.truncateTime(s.now()).Unix()
 	}
+}
 
-	// rotate files
-	rotateTime := truncateToHour(s.getNowTime())
-	ru := rotateTime.Unix()
-	_ = ru
-	if rotateTime.Unix() > s.lastRotateTime {
-		for i := levelMin; i <= levelMax; i++ {
-			originalFilename := s.writer[i].filePath
-			newFilename := originalFilename + "." + rotateTime.Format(datetimeSuffixLayout)
-			os.Rename(originalFilename, newFilename)
-		}
+func (s *FileBackend) truncateTime(t time.Time) time.Time {
+	if s.rotateInterval == RotateDaily {
+		return truncateToDay(t)
 	}
+	return truncateToHour(t)
+}
 
-	// remove old files
-	if s.keepHours <= 0 {
-		return
+func (s *FileBackend) suffixLayout() string {
+	if s.rotateInterval == RotateDaily {
+		return dailySuffixLayout
 	}
-	files, err := ioutil.ReadDir(s.dir)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "read dir %s failed: %v", s.dir, err)
-		return
+	return datetimeSuffixLayout
+}
+
+func (s *FileBackend) SetRotateBySize(maxBytes uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rotateSize = maxBytes
+}
+
+// SetRotateByLines rotates the file of a level with a numeric suffix after
+// n writes into it, 0 disables it.
+func (s *FileBackend) SetRotateByLines(n uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rotateLines = n
+}
+
+func (s *FileBackend) SetFileMode(fileMode, dirMode os.FileMode) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fileMode = fileMode
+	s.dirMode = dirMode
+	if err := os.Chmod(s.dir, dirMode); err != nil {
+		return err
 	}
-	for _, file := range files {
-		if file.Name() == s.rotatedFilenamePattern.FindString(file.Name()) &&
-			s.shouldDelete(file.Name(), s.keepHours) {
-			fullpath := filepath.Join(s.dir, file.Name())
-			if err := os.Remove(fullpath); err != nil {
-				fmt.Fprintf(os.Stderr, "remove %s failed: %v", fullpath, err)
-			}
+	for _, writer := range s.writers() {
+		if err := writer.file.Chmod(fileMode); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-func (s *FileBackend) doMonitorFiles() {
-	for i := levelMin; i <= levelMax; i++ {
-		if s.writer[i] == nil {
-			continue
-		}
+func (s *FileBackend) SetCompressRotated(compress bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.compressRotated = compress
+}
 
-		writer := s.writer[i]
-		filepath := writer.filePath
-		_, err := os.Stat(filepath)
-		if err == nil {
-			return
-		}
-		if !os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "stat %s failed: %v", filepath, err)
-			return
-		}
-		lockerWrapper := func(level Level, filepath string) error {
-			s.mutex.Lock()
-			defer s.mutex.Unlock()
-			return s.openSyncBufio(level, filepath)
-		}
-		if err := lockerWrapper(i, filepath); err != nil {
-			fmt.Fprintf(os.Stderr, "open %s failed: %v", filepath, err)
-			return
+func (s *FileBackend) SetBufferSize(n int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bufferSize = n
+	for _, writer := range s.writers() {
+		if err := writer.resize(n); err != nil {
+			return err
 		}
-		writer.close()
 	}
+	return nil
 }
 
-func (s *FileBackend) flush() {
-	for i := 0; i < int(levelCount); i++ {
-		if s.writer[i] == nil {
-			continue
-		}
+func (s *FileBackend) SetFormatter(formatter Formatter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.formatter = formatter
+}
+
+func (s *FileBackend) SetTimestampLayout(layout string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.timestampLayout = layout
+}
 
-		s.writer[i].flush()
-		s.writer[i].sync()
+func (s *FileBackend) SetMinLevel(level Level) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.minLevel = level
+	s.updateDisabledMask()
+	for _, i := range levels() {
+		if i.below(level) && s.writer[i] != nil {
+			s.closeLevel(i)
+		} else if !i.below(level) && s.writer[i] == nil && !s.lazyFileCreation {
+			if err := s.openLevel(i); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
 }
 
-func (s *FileBackend) Flush() {
+func (s *FileBackend) SetIncludeCaller(include bool) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.flush()
+	s.includeCaller = include
 }
 
-func (s *FileBackend) close() {
-	for i := 0; i < int(levelCount); i++ {
-		if err := s.writer[i].close(); err != nil {
-			fmt.Fprintf(os.Stderr, "close failed: %v", err)
-		}
-		s.writer[i] = nil
+// SetCallerSkip sets how many extra stack frames to skip when reporting the
+// caller, so wrappers around the backend report their own callers.
+func (s *FileBackend) SetCallerSkip(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callerSkip = n
+}
+
+// SetIncludeSequence prepends an increasing sequence number shared by all
+// levels to each line, so the files of different levels can be merged in
+// order.
+func (s *FileBackend) SetIncludeSequence(include bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.includeSequence = include
+}
+
+// SetEnsureNewline makes every line end with exactly one newline, missing
+// ones are added and extra ones are trimmed.
+func (s *FileBackend) SetEnsureNewline(ensure bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ensureNewline = ensure
+}
+
+func withOneNewline(content []byte) []byte {
+	body := bytes.TrimRight(content, "\n")
+	if len(body) == len(content)-1 {
+		return content
 	}
+	return append(body[:len(body):len(body)], '\n')
 }
 
-func (s *FileBackend) Close() {
+// SetMaxLineBytes truncates content longer than n bytes to at most n bytes
+// followed by a marker, without splitting a UTF-8 rune. The trailing newline
+// is kept and not counted. Zero means no limit.
+func (s *FileBackend) SetMaxLineBytes(n int) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.close()
+	s.maxLineBytes = n
 }
 
-func (s *FileBackend) shouldDelete(name string, keepHours int) bool {
-	datetimeSuffix := strings.Split(name, ".")[2]
-	fileTime, err := time.Parse(datetimeSuffixLayout, datetimeSuffix)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "parse datetime suffix failed, name: %v, err: %v", name, err)
-		return false
+func truncateLine(content []byte, n int) []byte {
+	body := bytes.TrimSuffix(content, []byte("\n"))
+	if len(body) <= n {
+		return content
 	}
-	fileTime = fileTime.Add(time.Duration(keepHours) * time.Hour)
-	removePoint := truncateToHour(s.getNowTime())
-	if !fileTime.After(removePoint) {
-		return true
+	cut := n
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
 	}
-	return false
+	result := make([]byte, 0, cut+len(truncatedMarker)+1)
+	result = append(result, body[:cut]...)
+	result = append(result, truncatedMarker...)
+	if len(body) < len(content) {
+		result = append(result, '\n')
+	}
+	return result
 }
 
-func (s *FileBackend) Log(level Level, content []byte) {
+// SetFileHeader sets the function generating the header written at the
+// start of each new file, including the ones created by rotation. In the
+// combined file, it is called with the lowest level. Current files which are
+// still empty get the header too.
+func (s *FileBackend) SetFileHeader(header func(level Level) []byte) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	if level >= levelMin && level <= levelMax {
-		s.writer[level].write(content)
-	} else {
-		fmt.Fprintf(os.Stderr, "invalid level: %v, content: %s", level, content)
+	s.fileHeader = header
+	if header == nil {
+		return nil
 	}
-	if level == Fatal {
-		s.flush()
+	written := make(map[*syncBufio]bool)
+	for _, i := range levels() {
+		writer := s.writer[i]
+		if writer == nil || written[writer] || writer.writeSize != 0 {
+			continue
+		}
+		written[writer] = true
+		if _, err := writer.write(header(i)); err != nil {
+			return err
+		}
+		writer.writeLines = 0
+	}
+	return nil
+}
+
+// RetentionPolicy decides which rotated files are removed, name is the base
+// name of a rotated file in the log directory.
+type RetentionPolicy interface {
+	ShouldDelete(name string, info os.FileInfo, now time.Time) bool
+}
+
+// SetSuffixByFirstWrite makes the suffix of a rotated file the hour or day
+// of the first log written into it, instead of the rotation time. Files
+// without logs written since opened keep the rotation time.
+func (s *FileBackend) SetSuffixByFirstWrite(byFirstWrite bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.suffixByFirstWrite = byFirstWrite
+}
+
+// SetRotateNameFunc sets the function returning the path a file is renamed
+// to when rotated at t, nil restores appending the datetime suffix. A
+// sequence is appended if the path already exists. Use
+// SetRotatedFilenamePattern to make the rotated files found by retention.
+func (s *FileBackend) SetRotateNameFunc(f func(originalPath string, t time.Time) string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rotateNameFunc = f
+}
+
+// SetRotatedFilenamePattern sets the regular expression matching the names
+// of rotated files. Its first group captures the file name of the level, and
+// the group named time captures the datetime in the layout of the rotate
+// interval. An empty pattern restores the default one.
+func (s *FileBackend) SetRotatedFilenamePattern(pattern string) error {
+	var customPattern *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if customPattern, err = regexp.Compile(pattern); err != nil {
+			return err
+		}
+		if customPattern.NumSubexp() < 1 || customPattern.SubexpIndex("time") < 0 {
+			return fmt.Errorf("pattern %q has no name group or time group", pattern)
+		}
 	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.customRotatedPattern = customPattern
+	s.updateRotatedFilenamePattern()
+	return nil
+}
+
+// SetRetentionPolicy replaces the removal by keepHours with p, nil restores
+// it. The size limit of SetMaxTotalBytes still applies to the kept files.
+func (s *FileBackend) SetRetentionPolicy(p RetentionPolicy) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.retentionPolicy = p
+}
+
+// SetOnRotate sets the callback invoked with each file rotated by time,
+// rotatedPath is the compressed file if compression is enabled. It runs in
+// the rotation goroutine without the backend locked.
+func (s *FileBackend) SetOnRotate(f func(level Level, rotatedPath string)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.onRotate = f
+}
+
+// SetStreamCompression writes the files compressed as LEVEL.log.gz while
+// logging, instead of compressing them after rotation. Flush ends a gzip
+// block so the written content can be decompressed from the live file.
+func (s *FileBackend) SetStreamCompression(compress bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.streamCompression == compress {
+		return nil
+	}
+	s.streamCompression = compress
+	return s.reopenLevels()
+}
+
+// SetExclusiveLock takes an exclusive advisory lock on each file opened, so
+// other processes enabling it fail to write the same files. It returns an
+// error if a file is locked by someone else.
+func (s *FileBackend) SetExclusiveLock(lock bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.exclusiveLock == lock {
+		return nil
+	}
+	s.exclusiveLock = lock
+	return s.reopenLevels()
+}
+
+// SetMirrorThreshold writes the logs at or above level into the file of
+// filepath additionally, a relative path is in the log directory. An empty
+// filepath stops mirroring.
+func (s *FileBackend) SetMirrorThreshold(level Level, filepath string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.mirror != nil {
+		if err := s.mirror.close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close failed: %v", err)
+		}
+		s.mirror = nil
+	}
+	if filepath == "" {
+		return nil
+	}
+	if !path.IsAbs(filepath) {
+		filepath = path.Join(s.dir, filepath)
+	}
+	mirror, err := s.openSyncBufio(filepath, level, s.truncateOnOpen && !s.writtenPaths[filepath])
+	if err != nil {
+		return err
+	}
+	s.mirror = mirror
+	s.mirrorLevel = level
+	return nil
+}
+
+// SetTruncateOnOpen makes the files truncated instead of appended when
+// opened for the first time, the files reopened by monitoring or rotation
+// are still appended. The files opened by NewFileBackend are truncated if
+// nothing has been written yet.
+func (s *FileBackend) SetTruncateOnOpen(truncate bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.truncateOnOpen = truncate
+	if !truncate {
+		return nil
+	}
+	for _, writer := range s.writers() {
+		if s.writtenPaths[writer.filePath] {
+			continue
+		}
+		if err := s.closeAndReopen(writer, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLazyFileCreation makes a level's file opened on the first log of the
+// level, files opened but never written are closed and removed.
+func (s *FileBackend) SetLazyFileCreation(lazy bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lazyFileCreation = lazy
+	if !lazy {
+		return
+	}
+	for _, writer := range s.writers() {
+		if writer.writeLines > 0 || !writer.createdEmpty {
+			continue
+		}
+		for level, other := range s.writer {
+			if other == writer {
+				s.writer[level] = nil
+			}
+		}
+		if err := writer.close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close failed: %v", err)
+		}
+		if err := os.Remove(writer.filePath); err != nil {
+			fmt.Fprintf(os.Stderr, "remove %s failed: %v", writer.filePath, err)
+		}
+	}
+}
+
+// SetCombinedFile switches between writing every level into one combined
+// file and the default one file per level.
+func (s *FileBackend) SetCombinedFile(combined bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.combinedFile == combined {
+		return nil
+	}
+	s.combinedFile = combined
+	return s.reopenLevels()
+}
+
+// SetLevelRouting writes each level in routing into the file named by its
+// value, e.g. {Warning: "problems", Error: "problems"} writes both levels
+// into problems.log. Levels not in routing keep their default files.
+func (s *FileBackend) SetLevelRouting(routing map[Level]string) error {
+	copied := make(map[Level]string, len(routing))
+	for level, name := range routing {
+		if !isValidLevel(level) {
+			return fmt.Errorf("invalid level: %v", level)
+		}
+		if name == "" || strings.ContainsAny(name, ". /\\") {
+			return fmt.Errorf("invalid file name: %q", name)
+		}
+		copied[level] = name
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.levelRouting = copied
+	s.updateRotatedFilenamePattern()
+	return s.reopenLevels()
+}
+
+func (s *FileBackend) updateRotatedFilenamePattern() {
+	names := make([]string, 0, len(s.levelRouting))
+	for _, name := range s.levelRouting {
+		names = append(names, name)
+	}
+	s.indexedFilenamePattern = newIndexedFilenamePattern(s.filePrefix, names...)
+	if s.customRotatedPattern != nil {
+		s.rotatedFilenamePattern = s.customRotatedPattern
+		return
+	}
+	s.rotatedFilenamePattern = newRotatedFilenamePattern(s.filePrefix, s.suffixLayout(), names...)
+}
+
+// SetFilePrefix names the files as prefix.LEVEL.log, so several backends
+// can share one directory.
+func (s *FileBackend) SetFilePrefix(prefix string) error {
+	if strings.ContainsAny(prefix, "/\\") {
+		return fmt.Errorf("invalid file prefix: %q", prefix)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.filePrefix == prefix {
+		return nil
+	}
+	s.filePrefix = prefix
+	s.updateRotatedFilenamePattern()
+	return s.reopenLevels()
+}
+
+// SetSyncEveryWrite makes every Log flush its buffer and fsync the file
+// before returning. Each write then costs a disk round trip, which is much
+// slower than the default interval flushing, use it only where every line
+// must be durable, e.g. audit logs.
+func (s *FileBackend) SetSyncEveryWrite(syncEveryWrite bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.syncEveryWrite = syncEveryWrite
+}
+
+// SetMaxTotalBytes caps the total size of rotated files, including the ones
+// rotated by size or lines, the oldest ones are removed once the cap is
+// exceeded. Zero means no limit.
+func (s *FileBackend) SetMaxTotalBytes(n uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxTotalBytes = n
+}
+
+func (s *FileBackend) IsLevelEnabled(level Level) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return !level.below(s.minLevel) && !s.disabledLevels[level] && isValidLevel(level)
+}
+
+// SetLevelEnabled turns a single level on or off independent of the min
+// level, so non-contiguous sets of levels can be written.
+func (s *FileBackend) SetLevelEnabled(level Level, enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if enabled {
+		delete(s.disabledLevels, level)
+	} else {
+		s.disabledLevels[level] = true
+	}
+	s.updateDisabledMask()
+}
+
+// updateDisabledMask stores the bits of the builtin levels disabled by the
+// min level or SetLevelEnabled, read before taking the mutex.
+func (s *FileBackend) updateDisabledMask() {
+	var mask uint32
+	for level := levelMin; level <= levelMax; level++ {
+		if level.below(s.minLevel) || s.disabledLevels[level] {
+			mask |= 1 << uint(level)
+		}
+	}
+	atomic.StoreUint32(&s.disabledMask, mask)
+}
+
+// disabledFast reports whether level is a builtin level disabled, without
+// the mutex held. Custom levels are checked with the mutex held.
+func (s *FileBackend) disabledFast(level Level) bool {
+	if level < levelMin || level > levelMax {
+		return false
+	}
+	return atomic.LoadUint32(&s.disabledMask)&(1<<uint(level)) != 0
+}
+
+// SetFlushInterval sets how often the files are flushed, a non-positive
+// interval flushes on every write.
+func (s *FileBackend) SetFlushInterval(t time.Duration) {
+	s.mutex.Lock()
+	s.flushInterval = t
+	s.mutex.Unlock()
+	select {
+	case s.flushIntervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+// SetMonitorInterval sets how often the backend checks for removed files, a
+// non-positive interval disables the check.
+func (s *FileBackend) SetMonitorInterval(d time.Duration) {
+	s.mutex.Lock()
+	s.monitorInterval = d
+	s.mutex.Unlock()
+	select {
+	case s.monitorIntervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+// SetClock replaces the source of the current time used for timestamps,
+// rotation and retention, nil restores time.Now. It is meant for tests.
+func (s *FileBackend) SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.getNowTime = now
+}
+
+// SetTimeZone makes rotation boundaries, rotated file suffixes and
+// timestamps use loc, nil uses the zone of the clock, which is local time
+// by default.
+func (s *FileBackend) SetTimeZone(loc *time.Location) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.location = loc
+	if s.rotateByHour {
+		s.lastRotateTime = s.truncateTime(s.now()).Unix()
+	}
+}
+
+func (s *FileBackend) now() time.Time {
+	now := s.getNowTime()
+	if s.location != nil {
+		now = now.In(s.location)
+	}
+	return now
+}
+
+// SetRotateCheckInterval sets how often the backend checks whether the files
+// should be rotated by time and removes the old ones, a non-positive interval
+// disables both.
+func (s *FileBackend) SetRotateCheckInterval(d time.Duration) {
+	s.mutex.Lock()
+	s.rotateCheckInterval = d
+	s.mutex.Unlock()
+	select {
+	case s.rotateCheckIntervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (s *FileBackend) getMonitorInterval() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.monitorInterval
+}
+
+func (s *FileBackend) getRotateCheckInterval() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.rotateCheckInterval
+}
+
+// SetLevelFlushInterval overrides the flush interval of level, zero flushes
+// the level on every write.
+func (s *FileBackend) SetLevelFlushInterval(level Level, d time.Duration) {
+	s.mutex.Lock()
+	s.levelFlushInterval[level] = d
+	s.mutex.Unlock()
+	select {
+	case s.flushIntervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (s *FileBackend) flushIntervalOf(level Level) time.Duration {
+	if d, ok := s.levelFlushInterval[level]; ok {
+		return d
+	}
+	return s.flushInterval
+}
+
+// getFlushInterval returns the period of the flush loop, which is the
+// shortest positive interval of all levels, zero if the levels are all
+// flushed on every write.
+func (s *FileBackend) getFlushInterval() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var interval time.Duration
+	for _, d := range s.levelFlushInterval {
+		if d > 0 && (interval == 0 || d < interval) {
+			interval = d
+		}
+	}
+	if d := s.flushInterval; d > 0 && (interval == 0 || d < interval) {
+		interval = d
+	}
+	return interval
+}
+
+// doFlush flushes the writers whose flush interval has elapsed.
+func (s *FileBackend) doFlush() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	intervals := make(map[*syncBufio]time.Duration)
+	for _, i := range levels() {
+		writer := s.writer[i]
+		if writer == nil {
+			continue
+		}
+		interval, ok := intervals[writer]
+		if d := s.flushIntervalOf(i); !ok || d < interval {
+			intervals[writer] = d
+		}
+	}
+	for _, i := range levels() {
+		writer := s.writer[i]
+		interval, ok := intervals[writer]
+		if !ok || now.Sub(writer.lastFlushTime) < interval {
+			continue
+		}
+		// shared writers are flushed once.
+		delete(intervals, writer)
+		if err := writer.flushAndSync(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			s.writeFailed(i, err)
+		}
+	}
+	if s.mirror != nil && now.Sub(s.mirror.lastFlushTime) >= s.flushIntervalOf(s.mirrorLevel) {
+		if err := s.mirror.flushAndSync(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			s.writeFailed(s.mirrorLevel, err)
+		}
+	}
+}
+
+type rotatedFile struct {
+	level Level
+	path  string
+}
+
+// rotate renames the current files with the suffix of rotateTime and opens
+// new ones, files failed to rename keep being written.
+func (s *FileBackend) rotate(rotateTime time.Time) ([]rotatedFile, error) {
+	var rotated []rotatedFile
+	var errs []error
+	for _, writer := range s.writers() {
+		originalFilename := writer.filePath
+		level := s.levelOf(writer)
+		suffixTime := rotateTime
+		if s.suffixByFirstWrite && !writer.firstWriteTime.IsZero() {
+			suffixTime = s.truncateTime(writer.firstWriteTime)
+		}
+		newFilename := s.rotatedPathOf(originalFilename, suffixTime)
+		if err := os.Rename(originalFilename, newFilename); err != nil {
+			errs = append(errs, fmt.Errorf("rename %s failed: %w", originalFilename, err))
+			continue
+		}
+		atomic.AddUint64(&s.rotations, 1)
+		if err := s.reopen(writer); err != nil {
+			errs = append(errs, fmt.Errorf("open %s failed: %w", originalFilename, err))
+			continue
+		}
+		rotated = append(rotated, rotatedFile{level: level, path: newFilename})
+	}
+	return rotated, errors.Join(errs...)
+}
+
+// afterRotate compresses the rotated files and calls the callback, without
+// the mutex held.
+func (s *FileBackend) afterRotate(rotated []rotatedFile, compress bool,
+	onRotate func(level Level, rotatedPath string)) {
+	for _, file := range rotated {
+		if compress && !strings.HasSuffix(file.path, gzipFileSuffix) {
+			if err := s.compressFile(file.path); err != nil {
+				fmt.Fprintf(os.Stderr, "compress %s failed: %v", file.path, err)
+			} else {
+				file.path += gzipFileSuffix
+			}
+		}
+		if onRotate != nil {
+			onRotate(file.level, file.path)
+		}
+	}
+}
+
+// RotateNow rotates all current files at once with the suffix of the current
+// time, regardless of the rotation interval.
+func (s *FileBackend) RotateNow() error {
+	s.mutex.Lock()
+	if s.isClosed() {
+		s.mutex.Unlock()
+		return fmt.Errorf("backend is closed")
+	}
+	rotated, err := s.rotate(s.truncateTime(s.now()))
+	compressRotated := s.compressRotated
+	onRotate := s.onRotate
+	s.mutex.Unlock()
+
+	s.afterRotate(rotated, compressRotated, onRotate)
+	return err
+}
+
+func (s *FileBackend) doRotateByHour() {
+	s.mutex.Lock()
+	// rotate files
+	var rotated []rotatedFile
+	rotateTime := s.truncateTime(s.now())
+	if s.rotateByHour && rotateTime.Unix() > s.lastRotateTime {
+		s.lastRotateTime = rotateTime.Unix()
+		var err error
+		if rotated, err = s.rotate(rotateTime); err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+		}
+	}
+	compressRotated := s.compressRotated
+	onRotate := s.onRotate
+	keepHours := s.keepHours
+	maxTotalBytes := s.maxTotalBytes
+	retentionPolicy := s.retentionPolicy
+	rotatedFilenamePattern := s.rotatedFilenamePattern
+	indexedFilenamePattern := s.indexedFilenamePattern
+	now := s.now()
+	s.mutex.Unlock()
+
+	s.afterRotate(rotated, compressRotated, onRotate)
+
+	// remove old files
+	if keepHours <= 0 && maxTotalBytes == 0 && retentionPolicy == nil {
+		return
+	}
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read dir %s failed: %v", s.dir, err)
+		return
+	}
+	var keptFiles []os.FileInfo
+	for _, file := range files {
+		timed := rotatedFilenamePattern.MatchString(file.Name())
+		if !timed && !indexedFilenamePattern.MatchString(file.Name()) {
+			continue
+		}
+		if retentionPolicy != nil {
+			if retentionPolicy.ShouldDelete(file.Name(), file, now) {
+				s.removeFile(file.Name())
+				continue
+			}
+		} else if keepHours > 0 {
+			// the files rotated by size are aged by the last write.
+			if (timed && s.shouldDelete(file.Name(), keepHours)) ||
+				(!timed && now.Sub(file.ModTime()) >= time.Duration(keepHours)*time.Hour) {
+				s.removeFile(file.Name())
+				continue
+			}
+		}
+		keptFiles = append(keptFiles, file)
+	}
+	if maxTotalBytes == 0 {
+		return
+	}
+
+	// remove the oldest files until total size fits.
+	sort.SliceStable(keptFiles, func(i, j int) bool {
+		return rotatedBefore(rotatedFilenamePattern, indexedFilenamePattern, keptFiles[i], keptFiles[j])
+	})
+	var totalBytes uint64
+	for _, file := range keptFiles {
+		totalBytes += uint64(file.Size())
+	}
+	for _, file := range keptFiles {
+		if totalBytes <= maxTotalBytes {
+			break
+		}
+		if s.removeFile(file.Name()) {
+			totalBytes -= uint64(file.Size())
+		}
+	}
+}
+
+// rotatedBefore reports whether the rotated file a is older than b, by the
+// time suffixes if both have one, or by the last write. The indexes only
+// break ties, since a freed index is taken again.
+func rotatedBefore(timePattern, indexPattern *regexp.Regexp, a, b os.FileInfo) bool {
+	aSuffix, aTimed := datetimeSuffixOf(timePattern, a.Name())
+	bSuffix, bTimed := datetimeSuffixOf(timePattern, b.Name())
+	if aTimed && bTimed && aSuffix != bSuffix {
+		return aSuffix < bSuffix
+	}
+	if !a.ModTime().Equal(b.ModTime()) || aTimed || bTimed {
+		return a.ModTime().Before(b.ModTime())
+	}
+	return indexOf(indexPattern, a.Name()) < indexOf(indexPattern, b.Name())
+}
+
+// indexOf returns the index of a file rotated by size, or 0 if name has none.
+func indexOf(pattern *regexp.Regexp, name string) int {
+	match := pattern.FindStringSubmatch(name)
+	if match == nil {
+		return 0
+	}
+	index, _ := strconv.Atoi(match[pattern.SubexpIndex("index")])
+	return index
+}
+
+// datetimeSuffixOf returns the datetime suffix of a rotated file name.
+func datetimeSuffixOf(pattern *regexp.Regexp, name string) (string, bool) {
+	match := pattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", false
+	}
+	return match[pattern.SubexpIndex("time")], true
+}
+
+func (s *FileBackend) removeFile(name string) bool {
+	fullpath := filepath.Join(s.dir, name)
+	if err := os.Remove(fullpath); err != nil {
+		fmt.Fprintf(os.Stderr, "remove %s failed: %v", fullpath, err)
+		return false
+	}
+	return true
+}
+
+// levelOf returns the lowest level written by writer.
+func (s *FileBackend) levelOf(writer *syncBufio) Level {
+	for _, i := range levels() {
+		if s.writer[i] == writer {
+			return i
+		}
+	}
+	return levelLowest
+}
+
+// closeAndReopen closes writer before opening the file again, used when the
+// lock of the old file blocks locking the same file, or the file is
+// truncated. Levels of writer are opened again on next write if it fails.
+func (s *FileBackend) closeAndReopen(writer *syncBufio, truncate bool) error {
+	level := s.levelOf(writer)
+	closeErr := writer.close()
+	newWriter, err := s.openSyncBufio(writer.filePath, level, truncate)
+	for i, other := range s.writer {
+		if other == writer {
+			s.writer[i] = newWriter
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// reopen replaces writer with a newly opened file of the same path.
+func (s *FileBackend) reopen(writer *syncBufio) error {
+	if s.exclusiveLock {
+		return s.closeAndReopen(writer, false)
+	}
+	newWriter, err := s.openSyncBufio(writer.filePath, s.levelOf(writer), false)
+	if err != nil {
+		return err
+	}
+	for level, other := range s.writer {
+		if other == writer {
+			s.writer[level] = newWriter
+		}
+	}
+	return writer.close()
+}
+
+// ReopenFiles flushes and closes the current files and opens them again by
+// name, e.g. from a SIGHUP handler after logrotate renamed them.
+func (s *FileBackend) ReopenFiles() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var firstErr error
+	for _, writer := range s.writers() {
+		if err := s.reopen(writer); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// ReopenLevel is like ReopenFiles but only reopens the file of level, which
+// is shared by the levels written into the same file.
+func (s *FileBackend) ReopenLevel(level Level) error {
+	if !isValidLevel(level) {
+		return fmt.Errorf("invalid level: %v", level)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	writer := s.writer[level]
+	if writer == nil {
+		return fmt.Errorf("level %s has no open file", levelNames[level])
+	}
+	return s.reopen(writer)
+}
+
+func (s *FileBackend) compressFile(filename string) error {
+	src, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	gzipFilename := filename + gzipFileSuffix
+	dst, err := os.OpenFile(gzipFilename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, s.fileMode)
+	if err != nil {
+		return err
+	}
+	gzipWriter := gzip.NewWriter(dst)
+	_, err = io.Copy(gzipWriter, src)
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(gzipFilename)
+		return err
+	}
+	return os.Remove(filename)
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// rotatedFilename appends a sequence to filename if it, or its compressed
+// variant, already exists, e.g. after rotating twice within one hour.
+func rotatedFilename(filename string) string {
+	newFilename := filename
+	for i := 1; fileExists(newFilename) || fileExists(newFilename+gzipFileSuffix); i++ {
+		newFilename = filename + "-" + strconv.Itoa(i)
+	}
+	return newFilename
+}
+
+// rotatedPathOf returns the path filename is renamed to when rotated at t.
+func (s *FileBackend) rotatedPathOf(filename string, t time.Time) string {
+	if s.rotateNameFunc != nil {
+		return rotatedFilename(s.rotateNameFunc(filename, t))
+	}
+	return withSuffix(filename, "."+t.Format(s.suffixLayout()), rotatedFilename)
+}
+
+// withSuffix names the rotated file of filename by name, keeping the .gz
+// extension of compressed files last.
+func withSuffix(filename string, suffix string, name func(string) string) string {
+	if strings.HasSuffix(filename, gzipFileSuffix) {
+		return name(strings.TrimSuffix(filename, gzipFileSuffix)+suffix) + gzipFileSuffix
+	}
+	return name(filename + suffix)
+}
+
+func nextIndexFilename(filename string) string {
+	for i := 1; ; i++ {
+		newFilename := filename + "." + strconv.Itoa(i)
+		if !fileExists(newFilename) && !fileExists(newFilename+gzipFileSuffix) {
+			return newFilename
+		}
+	}
+}
+
+func (s *FileBackend) rotateBySize(writer *syncBufio) {
+	if err := writer.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "flush %s failed: %v", writer.filePath, err)
+	}
+	newFilename := withSuffix(writer.filePath, "", nextIndexFilename)
+	if err := os.Rename(writer.filePath, newFilename); err != nil {
+		fmt.Fprintf(os.Stderr, "rename %s failed: %v", writer.filePath, err)
+		return
+	}
+	atomic.AddUint64(&s.rotations, 1)
+	if err := s.reopen(writer); err != nil {
+		fmt.Fprintf(os.Stderr, "open %s failed: %v", writer.filePath, err)
+	}
+}
+
+func (s *FileBackend) doMonitorFiles() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, writer := range s.writers() {
+		filepath := writer.filePath
+		_, err := os.Stat(filepath)
+		if err == nil {
+			continue
+		}
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "stat %s failed: %v", filepath, err)
+			continue
+		}
+		// the whole directory may be removed.
+		if err := os.MkdirAll(s.dir, s.dirMode); err != nil {
+			fmt.Fprintf(os.Stderr, "create %s failed: %v", s.dir, err)
+			return
+		}
+		if err := s.reopen(writer); err != nil {
+			fmt.Fprintf(os.Stderr, "open %s failed: %v", filepath, err)
+		}
+	}
+}
+
+func (s *FileBackend) flushAndSync() error {
+	var firstErr error
+	for _, writer := range s.mirrored(s.writers()) {
+		if err := writer.flushAndSync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Flush writes the buffered content into the files without syncing them,
+// the content may be lost if the machine crashes.
+func (s *FileBackend) Flush() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, writer := range s.mirrored(s.writers()) {
+		if err := writer.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "flush %s failed: %v", writer.filePath, err)
+		}
+	}
+}
+
+// FlushLevel is like Flush but only flushes the file of level, nothing is
+// done if the level has no open file.
+func (s *FileBackend) FlushLevel(level Level) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	writer := s.writer[level]
+	if writer == nil {
+		return nil
+	}
+	if err := writer.flush(); err != nil {
+		return fmt.Errorf("flush %s failed: %w", writer.filePath, err)
+	}
+	return nil
+}
+
+// WriteCurrentTo flushes level and copies its current file into w. The
+// content is the file as of the flush, logs written during the copy are left
+// out, and the copy is done without the mutex held.
+func (s *FileBackend) WriteCurrentTo(level Level, w io.Writer) (int64, error) {
+	s.mutex.Lock()
+	writer := s.writer[level]
+	if writer == nil {
+		s.mutex.Unlock()
+		return 0, fmt.Errorf("level %v has no open file", level)
+	}
+	if err := writer.flush(); err != nil {
+		s.mutex.Unlock()
+		return 0, fmt.Errorf("flush %s failed: %w", writer.filePath, err)
+	}
+	file, err := os.Open(writer.filePath)
+	var info os.FileInfo
+	if err == nil {
+		info, err = file.Stat()
+	}
+	s.mutex.Unlock()
+	if file != nil {
+		defer file.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, io.LimitReader(file, info.Size()))
+}
+
+// Sync commits the content already flushed into the files to the disk.
+func (s *FileBackend) Sync() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var firstErr error
+	for _, writer := range s.mirrored(s.writers()) {
+		if err := writer.sync(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("sync %s failed: %w", writer.filePath, err)
+		}
+	}
+	return firstErr
+}
+
+// FlushAndSync flushes the buffered content and syncs the files, like the
+// periodic flush does.
+func (s *FileBackend) FlushAndSync() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.flushAndSync()
+}
+
+func (s *FileBackend) close() {
+	for _, writer := range s.writers() {
+		if err := writer.close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close failed: %v", err)
+		}
+	}
+	for level := range s.writer {
+		s.writer[level] = nil
+	}
+}
+
+// mirrored returns writers with the mirror appended if any.
+func (s *FileBackend) mirrored(writers []*syncBufio) []*syncBufio {
+	if s.mirror != nil {
+		return append(writers, s.mirror)
+	}
+	return writers
+}
+
+func (s *FileBackend) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the files and waits for the background loops to stop, so it
+// must not be called from the callbacks of the backend.
+func (s *FileBackend) Close() {
+	s.mutex.Lock()
+	if !s.isClosed() {
+		close(s.done)
+	}
+	if err := s.writeHeld(); err != nil {
+		fmt.Fprintf(os.Stderr, "write held logs failed: %v", err)
+	}
+	s.close()
+	if s.mirror != nil {
+		if err := s.mirror.close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close failed: %v", err)
+		}
+		s.mirror = nil
+	}
+	s.mutex.Unlock()
+	// the loops take the mutex, so they are waited without it.
+	s.loops.Wait()
+}
+
+// CloseWithTimeout is like Close but returns an error once d elapses, the
+// flush blocked by a hanging writer is abandoned.
+func (s *FileBackend) CloseWithTimeout(d time.Duration) error {
+	return closeWithTimeout(s.Close, d)
+}
+
+func callerPrefix(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "???:0 "
+	}
+	return filepath.Base(file) + ":" + strconv.Itoa(line) + " "
+}
+
+func (s *FileBackend) statsOf(level Level) *levelStats {
+	value, ok := s.stats.Load(level)
+	if !ok {
+		value, _ = s.stats.LoadOrStore(level, &levelStats{})
+	}
+	return value.(*levelStats)
+}
+
+func (s *FileBackend) addStats(level Level, writeCount int, err error) {
+	stats := s.statsOf(level)
+	if err == nil {
+		atomic.AddUint64(&stats.lines, 1)
+	}
+	atomic.AddUint64(&stats.bytes, uint64(writeCount))
+}
+
+func (s *FileBackend) addDropped(level Level) {
+	atomic.AddUint64(&s.statsOf(level).dropped, 1)
+}
+
+// CurrentFilePath returns the path of the file level is written into, or an
+// empty string if the level has no open file.
+func (s *FileBackend) CurrentFilePath(level Level) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if writer := s.writer[level]; writer != nil {
+		return writer.filePath
+	}
+	return ""
+}
+
+// Stats returns the lines and bytes written, and the lines dropped by TryLog
+// or a full disk of each level since the backend was created. It is safe to
+// call concurrently with logging.
+func (s *FileBackend) Stats() map[Level]BackendStats {
+	result := make(map[Level]BackendStats)
+	s.stats.Range(func(key, value interface{}) bool {
+		stats := value.(*levelStats)
+		result[key.(Level)] = BackendStats{
+			Lines:   atomic.LoadUint64(&stats.lines),
+			Bytes:   atomic.LoadUint64(&stats.bytes),
+			Dropped: atomic.LoadUint64(&stats.dropped),
+		}
+		return true
+	})
+	return result
+}
+
+// Rotations returns the count of files rotated since the backend was
+// created, by time, size or lines.
+func (s *FileBackend) Rotations() uint64 {
+	return atomic.LoadUint64(&s.rotations)
+}
+
+// ListRotatedFiles returns the paths of the time rotated files of level,
+// newest first.
+func (s *FileBackend) ListRotatedFiles(level Level) ([]string, error) {
+	if !isValidLevel(level) {
+		return nil, fmt.Errorf("invalid level: %v", level)
+	}
+	s.mutex.Lock()
+	name := s.levelFileName(level)
+	pattern := s.rotatedFilenamePattern
+	s.mutex.Unlock()
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var rotatedFiles []os.FileInfo
+	for _, file := range files {
+		matches := pattern.FindStringSubmatch(file.Name())
+		if matches == nil || matches[1] != name {
+			continue
+		}
+		rotatedFiles = append(rotatedFiles, file)
+	}
+	sort.SliceStable(rotatedFiles, func(i, j int) bool {
+		iSuffix, _ := datetimeSuffixOf(pattern, rotatedFiles[i].Name())
+		jSuffix, _ := datetimeSuffixOf(pattern, rotatedFiles[j].Name())
+		if iSuffix != jSuffix {
+			return iSuffix > jSuffix
+		}
+		return rotatedFiles[i].ModTime().After(rotatedFiles[j].ModTime())
+	})
+	result := make([]string, 0, len(rotatedFiles))
+	for _, file := range rotatedFiles {
+		result = append(result, path.Join(s.dir, file.Name()))
+	}
+	return result, nil
+}
+
+func (s *FileBackend) shouldDelete(name string, keepHours int) bool {
+	datetimeSuffix, ok := datetimeSuffixOf(s.rotatedFilenamePattern, name)
+	if !ok {
+		return false
+	}
+	removePoint := s.truncateTime(s.now())
+	fileTime, err := time.ParseInLocation(s.suffixLayout(), datetimeSuffix, removePoint.Location())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse datetime suffix failed, name: %v, err: %v", name, err)
+		return false
+	}
+	fileTime = fileTime.Add(time.Duration(keepHours) * time.Hour)
+	if !fileTime.After(removePoint) {
+		return true
+	}
+	return false
+}
+
+func (s *FileBackend) Log(level Level, content []byte) {
+	if _, err := s.logDepth(1, level, content); err != nil {
+		fmt.Fprintf(os.Stderr, "%v, content: %s", err, content)
+	}
+}
+
+func (s *FileBackend) LogContext(ctx context.Context, level Level, content []byte) {
+	content = withTracePrefix(ctx, content)
+	if _, err := s.logDepth(1, level, content); err != nil {
+		fmt.Fprintf(os.Stderr, "%v, content: %s", err, content)
+	}
+}
+
+func (s *FileBackend) LogString(level Level, content string) {
+	if _, err := s.logDepth(1, level, []byte(content)); err != nil {
+		fmt.Fprintf(os.Stderr, "%v, content: %s", err, content)
+	}
+}
+
+// Logf formats the content like fmt.Sprintf, a newline is appended if it is
+// missing.
+func (s *FileBackend) Logf(level Level, format string, args ...interface{}) {
+	if !s.IsLevelEnabled(level) {
+		return
+	}
+	content := fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if _, err := s.logDepth(1, level, []byte(content)); err != nil {
+		fmt.Fprintf(os.Stderr, "%v, content: %s", err, content)
+	}
+}
+
+type levelWriter struct {
+	backend *FileBackend
+	level   Level
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	if _, err := w.backend.logDepth(1, w.level, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// LevelWriter returns an io.Writer logging everything written into it at
+// level, e.g. for log.New or http.Server.ErrorLog.
+func (s *FileBackend) LevelWriter(level Level) io.Writer {
+	return &levelWriter{
+		backend: s,
+		level:   level,
+	}
+}
+
+func (s *FileBackend) LogE(level Level, content []byte) error {
+	_, err := s.logDepth(1, level, content)
+	return err
+}
+
+// LogN is like LogE but also returns the count of bytes written into the
+// file, including the prefixes and the formatting.
+func (s *FileBackend) LogN(level Level, content []byte) (int, error) {
+	return s.logDepth(1, level, content)
+}
+
+// TryLog is like Log but drops content instead of waiting when the backend
+// is locked by others, returns false if dropped. The dropped lines are
+// counted in Stats.
+func (s *FileBackend) TryLog(level Level, content []byte) bool {
+	if s.disabledFast(level) {
+		return true
+	}
+	if !s.mutex.TryLock() {
+		s.addDropped(level)
+		return false
+	}
+	defer s.mutex.Unlock()
+	if _, err := s.log(1, level, content); err != nil {
+		fmt.Fprintf(os.Stderr, "%v, content: %s", err, content)
+	}
+	return true
+}
+
+// LogBatch writes lines of level while holding the lock once.
+func (s *FileBackend) LogBatch(level Level, lines [][]byte) {
+	if s.disabledFast(level) {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, line := range lines {
+		if _, err := s.log(1, level, line); err != nil {
+			fmt.Fprintf(os.Stderr, "%v, content: %s", err, line)
+		}
+	}
+}
+
+// logDepth writes content, depth is the count of frames between the caller
+// and logDepth.
+func (s *FileBackend) logDepth(depth int, level Level, content []byte) (int, error) {
+	if s.disabledFast(level) {
+		return 0, nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.log(depth+1, level, content)
+}
+
+// log is logDepth with the mutex held.
+func (s *FileBackend) log(depth int, level Level, content []byte) (int, error) {
+	if level.below(s.minLevel) || s.disabledLevels[level] {
+		return 0, nil
+	}
+	if !isValidLevel(level) {
+		return 0, fmt.Errorf("invalid level: %v", level)
+	}
+	if level == Fatal {
+		// whatever happens to the write.
+		defer s.flushOnFatal()
+	}
+	now := s.now()
+	if s.writer[level] == nil {
+		// level registered after the backend was created.
+		if s.isClosed() {
+			return 0, fmt.Errorf("writer of %s is closed", levelNames[level])
+		}
+		// a failed file is tried again after a while.
+		if now.Before(s.openRetryTime[level]) {
+			if s.fallbackWriter == nil {
+				return 0, fmt.Errorf("level %v has no open file", level)
+			}
+		} else if err := s.openLevel(level); err != nil {
+			s.openRetryTime[level] = now.Add(openRetryInterval)
+			if s.fallbackWriter == nil {
+				return 0, err
+			}
+		} else {
+			delete(s.openRetryTime, level)
+		}
+	}
+	if !s.writePausedUntil.IsZero() {
+		if err := s.resumeWrites(now); err != nil {
+			s.addDropped(level)
+			return 0, err
+		}
+	}
+
+	if s.encoder != nil {
+		content = s.encoder.Encode(level, now, content)
+	} else if !s.binarySafe {
+		content = s.decorate(depth+1, level, now, content)
+	}
+	// severe logs are written at once, since they may exit.
+	if s.paused && level != Fatal && level.below(s.flushOnLevel) {
+		return s.hold(level, now, content)
+	}
+	return s.write(level, now, content)
+}
+
+// write writes the decorated content into the file of level.
+func (s *FileBackend) write(level Level, now time.Time, content []byte) (int, error) {
+	writer := s.writer[level]
+	if writer == nil {
+		return s.writeFallback(level, content)
+	}
+	if writer.firstWriteTime.IsZero() {
+		writer.firstWriteTime = now
+		// the files written are not truncated when opened again.
+		s.writtenPaths[writer.filePath] = true
+	}
+	writeCount, err := writer.write(content)
+	s.addStats(level, writeCount, err)
+	if err != nil {
+		s.writeFailed(level, err)
+		return writeCount, err
+	}
+	mirrored := s.mirror != nil && !level.below(s.mirrorLevel)
+	if mirrored {
+		s.writtenPaths[s.mirror.filePath] = true
+		if _, err := s.mirror.write(content); err != nil {
+			s.writeFailed(level, err)
+			return writeCount, err
+		}
+	}
+	if (s.rotateSize > 0 && writer.writeSize >= s.rotateSize) ||
+		(s.rotateLines > 0 && writer.writeLines >= s.rotateLines) {
+		s.rotateBySize(writer)
+		writer = s.writer[level]
+	}
+	switch {
+	case level == Fatal:
+		// flushed by flushOnFatal regardless of the flush level.
+	case !level.below(s.flushOnLevel):
+		err = s.flushAndSync()
+	case s.syncEveryWrite || s.flushIntervalOf(level) <= 0:
+		err = writer.flushAndSync()
+		if mirrored && err == nil {
+			err = s.mirror.flushAndSync()
+		}
+	}
+	if err != nil {
+		s.writeFailed(level, err)
+	}
+	return writeCount, err
+}
+
+// flushOnFatal flushes and syncs the files of all levels, then exits if
+// SetExitOnFatal is set, even if the Fatal log itself failed.
+func (s *FileBackend) flushOnFatal() {
+	if err := s.flushAndSync(); err != nil {
+		s.writeFailed(Fatal, err)
+	}
+	if s.exitOnFatal {
+		s.exit(1)
+	}
+}
+
+// decorate applies the text options to content, depth is the count of frames
+// between the caller and decorate.
+func (s *FileBackend) decorate(depth int, level Level, now time.Time, content []byte) []byte {
+	if s.ensureNewline {
+		content = withOneNewline(content)
+	}
+	if s.maxLineBytes > 0 {
+		content = truncateLine(content, s.maxLineBytes)
+	}
+	if s.includeCaller {
+		content = append([]byte(callerPrefix(depth+1+s.callerSkip)), content...)
+	}
+	if s.levelFileName(level) != levelNames[level] && s.formatter == nil {
+		content = append([]byte(levelNames[level]+" "), content...)
+	}
+	if s.timestampLayout != "" {
+		content = append([]byte(now.Format(s.timestampLayout)+" "), content...)
+	}
+	if s.includeSequence {
+		sequence := atomic.AddUint64(&s.sequence, 1)
+		content = append([]byte(strconv.FormatUint(sequence, 10)+" "), content...)
+	}
+	if s.formatter != nil {
+		content = s.formatter.Format(level, now, content)
+	}
+	return content
+}
+
+// writeFallback writes the decorated content of a level without an open file
+// into the fallback writer, with the level name prefixed unless it is already.
+func (s *FileBackend) writeFallback(level Level, content []byte) (int, error) {
+	if s.fallbackWriter == nil {
+		s.addDropped(level)
+		return 0, fmt.Errorf("level %v has no open file", level)
+	}
+	named := s.levelFileName(level) != levelNames[level] && s.formatter == nil
+	if !named && !s.binarySafe && s.encoder == nil {
+		content = append([]byte(levelNames[level]+" "), content...)
+	}
+	writeCount, err := s.fallbackWriter.Write(content)
+	s.addStats(level, writeCount, err)
+	return writeCount, err
+}
+
+// writeFailed reports err to the callback, and pauses the writes for a while
+// if the disk is full.
+func (s *FileBackend) writeFailed(level Level, err error) {
+	if isNoSpace(err) {
+		s.writePausedUntil = s.now().Add(diskFullRetryInterval)
+	}
+	if s.onWriteError != nil {
+		s.onWriteError(level, err)
+	}
+}
+
+// resumeWrites reopens the files once the pause is over, since the buffered
+// writers keep returning the error they met.
+func (s *FileBackend) resumeWrites(now time.Time) error {
+	if now.Before(s.writePausedUntil) {
+		return errWritesPaused
+	}
+	for _, writer := range s.writers() {
+		if err := s.reopen(writer); err != nil {
+			s.writePausedUntil = now.Add(diskFullRetryInterval)
+			return err
+		}
+	}
+	s.writePausedUntil = time.Time{}
+	return nil
+}
+
+// SetWriterWrapper wraps the output of each file with f, which gets the
+// uncompressed content flushed from the buffer. The level of a file shared by
+// levels is the lowest one. The current files are reopened to apply it.
+func (s *FileBackend) SetWriterWrapper(f func(level Level, w io.Writer) io.Writer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.writerWrapper = f
+	var firstErr error
+	for _, writer := range s.writers() {
+		if err := s.reopen(writer); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SetFlushOnLevel makes a log at or above level flush and sync the files of
+// all levels immediately, Fatal by default. Fatal always does, and so do the
+// custom levels registered above Fatal unless level is raised above them.
+func (s *FileBackend) SetFlushOnLevel(level Level) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.flushOnLevel = level
+}
+
+// Pause stops writing the files until Resume. The logs in the meantime are
+// held, up to the capacity set by SetPauseBuffer, or dropped. Fatal and the
+// logs at or above the flush level are still written, ahead of the held ones.
+func (s *FileBackend) Pause() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.paused = true
+}
+
+// Resume writes the held logs in order and continues writing the files. It
+// returns the first error met, the rest of the logs are still written.
+func (s *FileBackend) Resume() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.paused = false
+	return s.writeHeld()
+}
+
+// SetPauseBuffer sets how many logs are held while paused, the logs over
+// capacity are dropped. All logs are dropped while paused if drop is true.
+func (s *FileBackend) SetPauseBuffer(capacity int, drop bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pauseCapacity = capacity
+	s.pauseDrop = drop
+}
+
+type heldEntry struct {
+	level   Level
+	time    time.Time
+	content []byte
+}
+
+func (s *FileBackend) hold(level Level, now time.Time, content []byte) (int, error) {
+	if s.pauseDrop || len(s.heldEntries) >= s.pauseCapacity {
+		s.addDropped(level)
+		return 0, nil
+	}
+	s.heldEntries = append(s.heldEntries, heldEntry{
+		level:   level,
+		time:    now,
+		content: append([]byte(nil), content...),
+	})
+	return len(content), nil
+}
+
+func (s *FileBackend) writeHeld() error {
+	var firstErr error
+	for _, entry := range s.heldEntries {
+		if _, err := s.write(entry.level, entry.time, entry.content); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.heldEntries = nil
+	return firstErr
+}
+
+// SetOnSlowIO sets the callback invoked when flushing or syncing a file, op
+// is "flush" or "sync", takes longer than threshold. It is called with the
+// backend locked, so it must not log into the same backend.
+func (s *FileBackend) SetOnSlowIO(threshold time.Duration, f func(op string, d time.Duration)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.slowIOThreshold = threshold
+	s.onSlowIO = f
+	slowIO := s.slowIO()
+	for _, writer := range s.mirrored(s.writers()) {
+		writer.slowIO = slowIO
+	}
+}
+
+// slowIO returns the function writers report the duration of operations to.
+func (s *FileBackend) slowIO() func(op string, d time.Duration) {
+	if s.onSlowIO == nil {
+		return nil
+	}
+	threshold, onSlowIO := s.slowIOThreshold, s.onSlowIO
+	return func(op string, d time.Duration) {
+		if d > threshold {
+			onSlowIO(op, d)
+		}
+	}
+}
+
+// SetExitOnFatal makes the process exit with code 1 once a Fatal log is
+// logged and all files are flushed, even if writing the log failed.
+func (s *FileBackend) SetExitOnFatal(exit bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.exitOnFatal = exit
+}
+
+// SetBinarySafe makes content written as is, ignoring the options assuming
+// text: the newline, the line size limit, the prefixes and the formatter.
+func (s *FileBackend) SetBinarySafe(binarySafe bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.binarySafe = binarySafe
+}
+
+// SetEncoder makes each entry written as the record encoded by e, instead
+// of the text line. Nil restores the text lines.
+func (s *FileBackend) SetEncoder(e Encoder) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.encoder = e
+}
+
+// SetFallbackWriter sets the writer logs of a level are written into, with
+// the level name prefixed, when the file of the level can not be opened,
+// os.Stderr by default. The file is tried again every 10 seconds. Nil
+// disables the fallback, such logs fail then.
+func (s *FileBackend) SetFallbackWriter(w io.Writer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fallbackWriter = w
+}
+
+// SetOnWriteError sets the callback invoked when writing or flushing a file
+// fails. It is called with the backend locked, so it must not log into the
+// same backend.
+func (s *FileBackend) SetOnWriteError(f func(Level, error)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.onWriteError = f
 }
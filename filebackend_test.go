@@ -1,16 +1,31 @@
 package golog
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
 	"os"
 	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 )
 
-func createFileBackend(t *testing.T) *FileBackend {
+func createFileBackend(t testing.TB) *FileBackend {
 	tempDir, err := ioutil.TempDir("", "fileBackend_test")
 	if err != nil {
 		t.Fatalf("create temporary directoey failed, err: %v", err)
@@ -22,6 +37,10 @@ func createFileBackend(t *testing.T) *FileBackend {
 	return fileBackend
 }
 
+func TestFileBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*FileBackend)(nil)
+}
+
 func TestTruncateToHour(t *testing.T) {
 	timeEdge := time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC)
 	timePoint := timeEdge.Add(time.Minute * 13)
@@ -70,6 +89,52 @@ func TestLogOutput(t *testing.T) {
 	}
 }
 
+func openFileCount(t *testing.T) int {
+	files, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("count open files failed, err: %v", err)
+	}
+	return len(files)
+}
+
+func TestNewFileBackendFallback(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "fileBackend_test")
+	if err != nil {
+		t.Fatalf("create temporary directoey failed, err: %v", err)
+	}
+	dir := path.Join(tempDir, "log")
+	// a directory can not be opened as the file of a later level.
+	if err := os.MkdirAll(path.Join(dir, levelNames[Error]+logFileSuffix), defaultDirMode); err != nil {
+		t.Fatalf("create directory failed, err: %v", err)
+	}
+
+	before := openFileCount(t)
+	fileBackend, err := NewFileBackend(dir)
+	if err != nil {
+		t.Fatalf("create file backend failed, err: %v", err)
+	}
+	var fallback bytes.Buffer
+	fileBackend.SetFallbackWriter(&fallback)
+	fileBackend.Log(Error, []byte("to fallback\n"))
+	fileBackend.Log(Info, []byte("to file\n"))
+	fileBackend.Close()
+
+	if expect := "ERROR to fallback\n"; fallback.String() != expect {
+		t.Errorf("fallback content not match, expect: %q, actual: %q", expect, fallback.String())
+	}
+	filePath := path.Join(dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if expect := "to file\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+	if after := openFileCount(t); after != before {
+		t.Errorf("opened files should be closed, before: %v, after: %v", before, after)
+	}
+}
+
 func TestMonitorDaemon(t *testing.T) {
 	fileBackend := createFileBackend(t)
 	defer fileBackend.Close()
@@ -83,9 +148,9 @@ func TestMonitorDaemon(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read temporary directory failed, err: %v", err)
 	}
-	if len(files) != levelCount {
+	if len(files) != len(levels()) {
 		t.Fatalf("count of log file should be %v, actual: %v",
-			levelCount, len(files))
+			len(levels()), len(files))
 	}
 	bakSuffix := ".bak"
 	for _, file := range files {
@@ -110,9 +175,9 @@ func TestMonitorDaemon(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read temporary directory failed, err: %v", err)
 	}
-	if len(files) != levelCount*2 {
+	if len(files) != len(levels())*2 {
 		t.Fatalf("count of log file should be %v, actual: %v",
-			levelCount*2, len(files))
+			len(levels())*2, len(files))
 	}
 	for _, file := range files {
 		filePath := path.Join(fileBackend.dir, file.Name())
@@ -131,6 +196,177 @@ func TestMonitorDaemon(t *testing.T) {
 	}
 }
 
+func TestMonitorRecreateDir(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	if err := fileBackend.SetFileMode(0600, 0750); err != nil {
+		t.Fatalf("set file mode failed, err: %v", err)
+	}
+
+	if err := os.RemoveAll(fileBackend.dir); err != nil {
+		t.Fatalf("remove %s failed, err: %v", fileBackend.dir, err)
+	}
+	fileBackend.doMonitorFiles()
+
+	info, err := os.Stat(fileBackend.dir)
+	if err != nil {
+		t.Fatalf("stat %s failed, err: %v", fileBackend.dir, err)
+	}
+	if !info.IsDir() || info.Mode().Perm()&^0750 != 0 {
+		t.Errorf("dir mode not match, actual: %v", info.Mode())
+	}
+	outputContent := "This is one string.\n"
+	fileBackend.Log(Info, []byte(outputContent))
+	fileBackend.Flush()
+	filePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if string(content) != outputContent {
+		t.Errorf("content not match, expect: %q, actual: %q", outputContent, content)
+	}
+}
+
+func TestMonitorReopenMiddleLevel(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Error]+logFileSuffix)
+	if err := os.Remove(logFilePath); err != nil {
+		t.Fatalf("remove %s failed, err: %v", logFilePath, err)
+	}
+	fileBackend.doMonitorFiles()
+	if _, err := os.Stat(logFilePath); err != nil {
+		t.Fatalf("%s should be recreated, err: %v", logFilePath, err)
+	}
+
+	outputContent := "This is one string."
+	fileBackend.Log(Error, []byte(outputContent))
+	fileBackend.Flush()
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if string(content) != outputContent {
+		t.Errorf("content not match, expect: %s, write: %s", outputContent, content)
+	}
+}
+
+func TestReopenFiles(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	fileBackend.Log(Info, []byte("before rename\n"))
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	renamedFilePath := logFilePath + ".1"
+	if err := os.Rename(logFilePath, renamedFilePath); err != nil {
+		t.Fatalf("move %s failed, err: %v", logFilePath, err)
+	}
+	if err := fileBackend.ReopenFiles(); err != nil {
+		t.Fatalf("reopen files failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("after rename\n"))
+	fileBackend.Flush()
+
+	expects := map[string]string{
+		renamedFilePath: "before rename\n",
+		logFilePath:     "after rename\n",
+	}
+	for filePath, expectContent := range expects {
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", filePath, err)
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s content not match, expect: %s, write: %s", filePath, expectContent, content)
+		}
+	}
+}
+
+func TestReopenLevel(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	// the monitor would reopen the file of ERROR.
+	fileBackend.SetMonitorInterval(time.Hour)
+
+	for _, level := range []Level{Info, Error} {
+		fileBackend.Log(level, []byte("before rename\n"))
+	}
+	fileBackend.Flush()
+	for _, level := range []Level{Info, Error} {
+		filePath := path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)
+		if err := os.Rename(filePath, filePath+".1"); err != nil {
+			t.Fatalf("move %s failed, err: %v", filePath, err)
+		}
+	}
+	if err := fileBackend.ReopenLevel(Info); err != nil {
+		t.Fatalf("reopen level failed, err: %v", err)
+	}
+	if err := fileBackend.ReopenLevel(Level(100)); err == nil {
+		t.Errorf("reopen invalid level should fail")
+	}
+	for _, level := range []Level{Info, Error} {
+		fileBackend.Log(level, []byte("after rename\n"))
+	}
+	fileBackend.Flush()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	expects := map[string]string{
+		logFilePath + ".1": "before rename\n",
+		logFilePath:        "after rename\n",
+		// the file of ERROR is still the renamed one.
+		path.Join(fileBackend.dir, levelNames[Error]+logFileSuffix+".1"): "before rename\nafter rename\n",
+	}
+	for filePath, expectContent := range expects {
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", filePath, err)
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s content not match, expect: %s, write: %s", filePath, expectContent, content)
+		}
+	}
+	if _, err := os.Stat(path.Join(fileBackend.dir, levelNames[Error]+logFileSuffix)); !os.IsNotExist(err) {
+		t.Errorf("file of ERROR should not be reopened, err: %v", err)
+	}
+}
+
+func TestMonitorConcurrentLog(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	done := make(chan struct{})
+	monitorDone := make(chan struct{})
+	go func() {
+		defer close(monitorDone)
+		logFilePath := path.Join(fileBackend.dir, levelNames[Debug]+logFileSuffix)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			os.Remove(logFilePath)
+			fileBackend.doMonitorFiles()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				fileBackend.Log(Level(j%len(levels())), []byte("This is one string.\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	<-monitorDone
+}
+
 func TestRoratedFilenamePattern(t *testing.T) {
 	filename := "DEBUG.log.2019061012"
 	matchedString := rotatedFilenamePattern.FindString(filename)
@@ -139,6 +375,35 @@ func TestRoratedFilenamePattern(t *testing.T) {
 	}
 }
 
+func TestRoratedFilenamePatternGzip(t *testing.T) {
+	filename := "DEBUG.log.2019061012.gz"
+	matchedString := rotatedFilenamePattern.FindString(filename)
+	if filename != matchedString {
+		t.Errorf("actual: %v, expect: %v", matchedString, filename)
+	}
+}
+
+func TestTruncateToDay(t *testing.T) {
+	timeEdge := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	timePoint := timeEdge.Add(time.Hour*13 + time.Minute*13)
+	timeTruncated := truncateToDay(timePoint)
+	if timeTruncated != timeEdge {
+		t.Fatalf("expected: %v, return: %v",
+			timeEdge.String(), timeTruncated.String())
+	}
+}
+
+func TestDailyRotatedFilenamePattern(t *testing.T) {
+	filename := "DEBUG.log.20190610"
+	if matchedString := dailyRotatedFilenamePattern.FindString(filename); filename != matchedString {
+		t.Errorf("actual: %v, expect: %v", matchedString, filename)
+	}
+	filename = "DEBUG.log.2019061012"
+	if matchedString := dailyRotatedFilenamePattern.FindString(filename); filename == matchedString {
+		t.Errorf("hourly file should not match daily pattern: %v", filename)
+	}
+}
+
 func TestShouldDelete(t *testing.T) {
 	fileBackend := createFileBackend(t)
 	defer fileBackend.Close()
@@ -175,9 +440,9 @@ func TestRotate(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read temporary directory failed, err: %v", err)
 	}
-	if len(files) != levelCount {
+	if len(files) != len(levels()) {
 		t.Fatalf("count of log file should be %v, actual: %v",
-			levelCount, len(files))
+			len(levels()), len(files))
 	}
 
 	// trigger rotate.
@@ -207,9 +472,9 @@ func TestRotate(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read temporary directory failed, err: %v", err)
 	}
-	if len(files) != levelCount*2 {
+	if len(files) != len(levels())*2 {
 		t.Errorf("count of log file should be %v, actual: %v",
-			levelCount*2, len(files))
+			len(levels())*2, len(files))
 	}
 	timeSuffix := nowTime.Format(datetimeSuffixLayout)
 	for _, file := range files {
@@ -228,3 +493,2561 @@ func TestRotate(t *testing.T) {
 		}
 	}
 }
+
+func TestSuffixByFirstWrite(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	// rotate by the test only.
+	fileBackend.SetRotateCheckInterval(time.Hour)
+
+	nowTime := time.Date(2019, 6, 10, 11, 59, 59, 0, time.UTC)
+	fileBackend.SetClock(func() time.Time {
+		return nowTime
+	})
+	fileBackend.SetRotateFile(true, 0)
+	fileBackend.SetSuffixByFirstWrite(true)
+	fileBackend.Log(Info, []byte("written at 11:59\n"))
+
+	nowTime = nowTime.Add(2 * time.Second)
+	fileBackend.doRotateByHour()
+
+	rotatedFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix+".2019061011")
+	content, err := ioutil.ReadFile(rotatedFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", rotatedFilePath, err)
+	}
+	if expect := "written at 11:59\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+	// nothing written into DEBUG, so the rotation time is used.
+	rotatedFilePath = path.Join(fileBackend.dir, levelNames[Debug]+logFileSuffix+".2019061012")
+	if _, err := os.Stat(rotatedFilePath); err != nil {
+		t.Errorf("stat %s failed, err: %v", rotatedFilePath, err)
+	}
+}
+
+func TestRotateNameFunc(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	// rotate by the test only.
+	fileBackend.SetRotateCheckInterval(time.Hour)
+
+	nowTime := time.Date(2019, 6, 10, 12, 13, 14, 0, time.UTC)
+	fileBackend.SetClock(func() time.Time {
+		return nowTime
+	})
+	fileBackend.SetRotateFile(true, 2)
+	fileBackend.SetRotateNameFunc(func(originalPath string, t time.Time) string {
+		return strings.TrimSuffix(originalPath, logFileSuffix) + "-" + t.Format(datetimeSuffixLayout) + logFileSuffix
+	})
+	if err := fileBackend.SetRotatedFilenamePattern(`^(INFO)-(?P<time>[0-9]{4}`); err == nil {
+		t.Errorf("invalid pattern should fail")
+	}
+	if err := fileBackend.SetRotatedFilenamePattern(`^(DEBUG|INFO)-[0-9]{10}\.log$`); err == nil {
+		t.Errorf("pattern without time group should fail")
+	}
+	if err := fileBackend.SetRotatedFilenamePattern(`^([A-Z]+)-(?P<time>[0-9]{10})\.log(-[0-9]+)?(\.gz)?$`); err != nil {
+		t.Fatalf("set rotated filename pattern failed, err: %v", err)
+	}
+	oldName := "INFO-" + nowTime.Add(-3*time.Hour).Format(datetimeSuffixLayout) + logFileSuffix
+	if err := ioutil.WriteFile(path.Join(fileBackend.dir, oldName), []byte("old\n"), 0644); err != nil {
+		t.Fatalf("write %s failed, err: %v", oldName, err)
+	}
+	fileBackend.Log(Info, []byte("before rotate\n"))
+
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.doRotateByHour()
+
+	rotatedName := "INFO-" + truncateToHour(nowTime).Format(datetimeSuffixLayout) + logFileSuffix
+	content, err := ioutil.ReadFile(path.Join(fileBackend.dir, rotatedName))
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", rotatedName, err)
+	}
+	if string(content) != "before rotate\n" {
+		t.Errorf("content not match, expect: %q, actual: %q", "before rotate\n", content)
+	}
+	if _, err := os.Stat(path.Join(fileBackend.dir, oldName)); !os.IsNotExist(err) {
+		t.Errorf("%s should be removed, err: %v", oldName, err)
+	}
+	rotatedFiles, err := fileBackend.ListRotatedFiles(Info)
+	if err != nil {
+		t.Fatalf("list rotated files failed, err: %v", err)
+	}
+	if len(rotatedFiles) != 1 || path.Base(rotatedFiles[0]) != rotatedName {
+		t.Errorf("rotated files not match, actual: %v", rotatedFiles)
+	}
+}
+
+func TestSetKeepHours(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	// rotate by the test only.
+	fileBackend.SetRotateCheckInterval(time.Hour)
+
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.SetClock(func() time.Time {
+		return nowTime
+	})
+	fileBackend.SetRotateFile(true, 0)
+	if err := fileBackend.SetKeepHours(-1); err == nil {
+		t.Errorf("negative keep hours should fail")
+	}
+
+	// rotated files of 3 hours ago and 1 hour ago.
+	var rotatedFiles []string
+	for _, hours := range []int{3, 1} {
+		name := levelNames[Info] + logFileSuffix + "." +
+			nowTime.Add(-time.Hour*time.Duration(hours)).Format(datetimeSuffixLayout)
+		rotatedFiles = append(rotatedFiles, name)
+		if err := ioutil.WriteFile(path.Join(fileBackend.dir, name), []byte("x\n"), 0644); err != nil {
+			t.Fatalf("write %s failed, err: %v", name, err)
+		}
+	}
+	fileBackend.doRotateByHour()
+	for _, name := range rotatedFiles {
+		if _, err := os.Stat(path.Join(fileBackend.dir, name)); err != nil {
+			t.Errorf("%s should be kept forever, err: %v", name, err)
+		}
+	}
+
+	if err := fileBackend.SetKeepHours(2); err != nil {
+		t.Fatalf("set keep hours failed, err: %v", err)
+	}
+	if actual := fileBackend.KeepHours(); actual != 2 {
+		t.Errorf("keep hours not match, expect: 2, actual: %v", actual)
+	}
+	fileBackend.doRotateByHour()
+	if _, err := os.Stat(path.Join(fileBackend.dir, rotatedFiles[0])); !os.IsNotExist(err) {
+		t.Errorf("%s should be removed, err: %v", rotatedFiles[0], err)
+	}
+	if _, err := os.Stat(path.Join(fileBackend.dir, rotatedFiles[1])); err != nil {
+		t.Errorf("%s should be kept, err: %v", rotatedFiles[1], err)
+	}
+	// the rotation state is not reset.
+	if expect := truncateToHour(nowTime).Unix(); fileBackend.lastRotateTime != expect {
+		t.Errorf("last rotate time not match, expect: %v, actual: %v", expect, fileBackend.lastRotateTime)
+	}
+}
+
+// TestRotateConcurrentLog checks the lines logged before a rotation are all
+// in the rotated file and the later ones in the new current file, run it
+// with -race.
+func TestRotateConcurrentLog(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetIncludeSequence(true)
+
+	const writerCount = 4
+	const linesPerWriter = 500
+	var wg sync.WaitGroup
+	for i := 0; i < writerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < linesPerWriter; j++ {
+				fileBackend.Log(Info, []byte("line\n"))
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	if err := fileBackend.RotateNow(); err != nil {
+		t.Fatalf("rotate failed, err: %v", err)
+	}
+	wg.Wait()
+	fileBackend.Flush()
+
+	rotatedFiles, err := fileBackend.ListRotatedFiles(Info)
+	if err != nil || len(rotatedFiles) != 1 {
+		t.Fatalf("list rotated files failed, files: %v, err: %v", rotatedFiles, err)
+	}
+	sequencesOf := func(filePath string) []int {
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", filePath, err)
+		}
+		var sequences []int
+		for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			var sequence int
+			if _, err := fmt.Sscanf(line, "%d line", &sequence); err != nil {
+				t.Fatalf("invalid line %q, err: %v", line, err)
+			}
+			sequences = append(sequences, sequence)
+		}
+		return sequences
+	}
+	rotated := sequencesOf(rotatedFiles[0])
+	current := sequencesOf(fileBackend.CurrentFilePath(Info))
+	if len(rotated)+len(current) != writerCount*linesPerWriter {
+		t.Fatalf("count of lines not match, expect: %v, actual: %v",
+			writerCount*linesPerWriter, len(rotated)+len(current))
+	}
+	for i, sequence := range append(rotated, current...) {
+		if sequence != i+1 {
+			t.Fatalf("line %v should have sequence %v, actual: %v", i, i+1, sequence)
+		}
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetRotateBySize(32)
+
+	outputContent := "This line is longer than limit.\n"
+	fileBackend.Log(Debug, []byte(outputContent))
+	fileBackend.Log(Debug, []byte(outputContent))
+	fileBackend.Flush()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Debug]+logFileSuffix)
+	for _, filePath := range []string{logFilePath + ".1", logFilePath + ".2"} {
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", filePath, err)
+		}
+		if string(content) != outputContent {
+			t.Errorf("%s not match, expect: %s, write: %s", filePath, outputContent, content)
+		}
+	}
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if len(content) != 0 {
+		t.Errorf("current file should be empty, actual: %s", content)
+	}
+	if matched := rotatedFilenamePattern.FindString(levelNames[Debug] + logFileSuffix + ".1"); matched != "" {
+		t.Errorf("size rotated file should not match rotated pattern, matched: %v", matched)
+	}
+}
+
+func TestRotateByLines(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetRotateByLines(3)
+
+	for i := 0; i < 4; i++ {
+		fileBackend.Log(Info, []byte(fmt.Sprintf("line %d\n", i)))
+	}
+	fileBackend.Flush()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	expects := map[string]string{
+		logFilePath + ".1": "line 0\nline 1\nline 2\n",
+		logFilePath:        "line 3\n",
+	}
+	for filePath, expectContent := range expects {
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", filePath, err)
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s not match, expect: %s, write: %s", filePath, expectContent, content)
+		}
+	}
+}
+
+func TestFileHeader(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	err := fileBackend.SetFileHeader(func(level Level) []byte {
+		return []byte("# " + level.String() + "\n")
+	})
+	if err != nil {
+		t.Fatalf("set file header failed, err: %v", err)
+	}
+	fileBackend.SetRotateByLines(2)
+
+	for i := 0; i < 3; i++ {
+		fileBackend.Log(Warning, []byte(fmt.Sprintf("line %d\n", i)))
+	}
+	fileBackend.Flush()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Warning]+logFileSuffix)
+	expects := map[string]string{
+		logFilePath + ".1": "# WARNING\nline 0\nline 1\n",
+		logFilePath:        "# WARNING\nline 2\n",
+	}
+	for filePath, expectContent := range expects {
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", filePath, err)
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s not match, expect: %s, write: %s", filePath, expectContent, content)
+		}
+	}
+}
+
+func TestSetFileMode(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	if err := fileBackend.SetFileMode(0600, 0700); err != nil {
+		t.Fatalf("set file mode failed, err: %v", err)
+	}
+	info, err := os.Stat(fileBackend.dir)
+	if err != nil {
+		t.Fatalf("stat %s failed, err: %v", fileBackend.dir, err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("dir mode should be 0700, actual: %v", info.Mode().Perm())
+	}
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Debug]+logFileSuffix)
+	checkFileMode := func() {
+		info, err := os.Stat(logFilePath)
+		if err != nil {
+			t.Fatalf("stat %s failed, err: %v", logFilePath, err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("file mode should be 0600, actual: %v", info.Mode().Perm())
+		}
+	}
+	checkFileMode()
+
+	// reopened file should use the configured mode too.
+	if err := os.Remove(logFilePath); err != nil {
+		t.Fatalf("remove %s failed, err: %v", logFilePath, err)
+	}
+	fileBackend.doMonitorFiles()
+	checkFileMode()
+}
+
+func TestCompressRotated(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.SetRotateFile(true, 0)
+	fileBackend.SetCompressRotated(true)
+
+	outputContent := "This is one string."
+	fileBackend.Log(Info, []byte(outputContent))
+	fileBackend.Flush()
+
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.doRotateByHour()
+
+	rotatedFilePath := path.Join(fileBackend.dir,
+		levelNames[Info]+logFileSuffix+"."+nowTime.Format(datetimeSuffixLayout))
+	if _, err := os.Stat(rotatedFilePath); !os.IsNotExist(err) {
+		t.Errorf("uncompressed rotated file should be removed, err: %v", err)
+	}
+	file, err := os.Open(rotatedFilePath + gzipFileSuffix)
+	if err != nil {
+		t.Fatalf("open compressed file failed, err: %v", err)
+	}
+	defer file.Close()
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("create gzip reader failed, err: %v", err)
+	}
+	content, err := ioutil.ReadAll(gzipReader)
+	if err != nil {
+		t.Fatalf("decompress failed, err: %v", err)
+	}
+	if string(content) != outputContent {
+		t.Errorf("compressed content not match, expect: %s, actual: %s", outputContent, content)
+	}
+}
+
+func TestSetBufferSize(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	if err := fileBackend.SetBufferSize(16); err != nil {
+		t.Fatalf("set buffer size failed, err: %v", err)
+	}
+	if fileBackend.writer[Info].writer.Size() != 16 {
+		t.Errorf("buffer size should be 16, actual: %v", fileBackend.writer[Info].writer.Size())
+	}
+
+	var expectContent strings.Builder
+	for i := 0; i < 1000; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		expectContent.WriteString(line)
+		fileBackend.Log(Info, []byte(line))
+	}
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if string(content) != expectContent.String() {
+		t.Errorf("content not match, expect %v bytes, actual %v bytes",
+			expectContent.Len(), len(content))
+	}
+}
+
+func TestSetFormatter(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	timePoint := time.Date(2019, 6, 10, 12, 0, 0, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return timePoint
+	}
+	fileBackend.SetFormatter(&JSONFormatter{})
+	fileBackend.Log(Warning, []byte("This is a warning string.\n"))
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Warning]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	expectContent := `{"ts":"2019-06-10T12:00:00Z","level":"WARNING","msg":"This is a warning string."}` + "\n"
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func TestSetTimestampLayout(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	timePoint := time.Date(2019, 6, 10, 12, 0, 0, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return timePoint
+	}
+	fileBackend.SetTimestampLayout(time.RFC3339)
+	fileBackend.Log(Debug, []byte("This is a debug string\n"))
+	fileBackend.SetTimestampLayout("")
+	fileBackend.Log(Debug, []byte("This is another debug string\n"))
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Debug]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	expectContent := "2019-06-10T12:00:00Z This is a debug string\n" +
+		"This is another debug string\n"
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func TestRotateDaily(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	nowTime := time.Date(2019, 6, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.SetRotateInterval(RotateDaily)
+	fileBackend.SetRotateFile(true, 24)
+
+	outputContent := "This is one string."
+	fileBackend.Log(Info, []byte(outputContent))
+	fileBackend.Flush()
+
+	// same day, nothing rotated.
+	nowTime = nowTime.Add(time.Hour * 5)
+	fileBackend.doRotateByHour()
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	if _, err := os.Stat(logFilePath + ".20190610"); !os.IsNotExist(err) {
+		t.Fatalf("should not rotate within the same day, err: %v", err)
+	}
+
+	// next day, rotated with daily suffix.
+	nowTime = nowTime.Add(time.Hour * 24)
+	fileBackend.doRotateByHour()
+	content, err := ioutil.ReadFile(logFilePath + ".20190611")
+	if err != nil {
+		t.Fatalf("read rotated file failed, err: %v", err)
+	}
+	if string(content) != outputContent {
+		t.Errorf("content not match, expect: %s, write: %s", outputContent, content)
+	}
+
+	// retention keeps one day.
+	if fileBackend.shouldDelete(levelNames[Info]+logFileSuffix+".20190611", 24) {
+		t.Errorf("file of yesterday should be kept")
+	}
+	if !fileBackend.shouldDelete(levelNames[Info]+logFileSuffix+".20190610", 24) {
+		t.Errorf("file of two days ago should be deleted")
+	}
+}
+
+func TestSetMinLevel(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	if err := fileBackend.SetMinLevel(Warning); err != nil {
+		t.Fatalf("set min level failed, err: %v", err)
+	}
+	if fileBackend.writer[Debug] != nil || fileBackend.writer[Info] != nil {
+		t.Errorf("writers below min level should be closed")
+	}
+
+	outputContent := "This is one string."
+	for level := levelMin; level <= levelMax; level++ {
+		fileBackend.Log(level, []byte(outputContent))
+	}
+	fileBackend.Close()
+
+	for level := levelMin; level <= levelMax; level++ {
+		logFilePath := path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)
+		content, err := ioutil.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", logFilePath, err)
+		}
+		expectContent := outputContent
+		if level < Warning {
+			expectContent = ""
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s log not match, expect: %s, write: %s",
+				levelNames[level], expectContent, content)
+		}
+	}
+}
+
+func TestSetAfterCloseOpensNothing(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	if err := fileBackend.SetMinLevel(Warning); err != nil {
+		t.Fatalf("set min level failed, err: %v", err)
+	}
+	fileBackend.Close()
+
+	if err := fileBackend.SetMinLevel(Debug); err == nil {
+		t.Errorf("set min level after close should fail")
+	}
+	if err := fileBackend.SetFilePrefix("prefix"); err == nil {
+		t.Errorf("set file prefix after close should fail")
+	}
+	if writers := fileBackend.writers(); len(writers) != 0 {
+		t.Errorf("no writer should be open after close, actual: %d", len(writers))
+	}
+}
+
+func TestSetLevelEnabled(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	for _, level := range []Level{Debug, Info, Warning} {
+		fileBackend.SetLevelEnabled(level, false)
+	}
+	fileBackend.SetLevelEnabled(Info, true)
+	fileBackend.SetLevelEnabled(Info, false)
+	if fileBackend.IsLevelEnabled(Warning) || !fileBackend.IsLevelEnabled(Error) {
+		t.Errorf("only error and fatal should be enabled")
+	}
+
+	outputContent := "This is one string."
+	for level := levelMin; level <= levelMax; level++ {
+		fileBackend.Log(level, []byte(outputContent))
+	}
+	fileBackend.Close()
+
+	for level := levelMin; level <= levelMax; level++ {
+		logFilePath := path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)
+		content, err := ioutil.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", logFilePath, err)
+		}
+		expectContent := outputContent
+		if level < Error {
+			expectContent = ""
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s log not match, expect: %s, write: %s",
+				levelNames[level], expectContent, content)
+		}
+	}
+}
+
+var errTestWrite = errors.New("no space left on device")
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errTestWrite
+}
+
+func TestLogEReturnsWriteError(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	if err := fileBackend.LogE(Info, []byte("This is one string.")); err != nil {
+		t.Fatalf("log should succeed, err: %v", err)
+	}
+	fileBackend.writer[Info].writer = bufio.NewWriterSize(failingWriter{}, 16)
+	err := fileBackend.LogE(Info, []byte("This string is longer than the buffer."))
+	if !errors.Is(err, errTestWrite) {
+		t.Errorf("write error should propagate, actual: %v", err)
+	}
+	if err := fileBackend.LogE(Level(100), []byte("This is one string.")); err == nil {
+		t.Errorf("invalid level should return error")
+	}
+}
+
+// partialWriter accepts the first 3 bytes of each write then fails.
+type partialWriter struct{}
+
+func (partialWriter) Write(p []byte) (int, error) {
+	if len(p) > 3 {
+		return 3, errTestWrite
+	}
+	return len(p), nil
+}
+
+func TestBinarySafe(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.SetBinarySafe(true)
+	fileBackend.SetEnsureNewline(true)
+	fileBackend.SetTimestampLayout("15:04")
+	fileBackend.SetFormatter(&JSONFormatter{})
+	chunks := [][]byte{
+		{0x00, 0x01, 'a', 0x00},
+		{'\n', '\n', 0xff, 0xfe},
+	}
+	for _, chunk := range chunks {
+		fileBackend.Log(Info, chunk)
+	}
+	writer := fileBackend.writer[Info]
+	if writer.writeSize != 8 || writer.writeLines != 2 {
+		t.Errorf("counters not match, expect: 8 bytes 2 lines, actual: %v bytes %v lines",
+			writer.writeSize, writer.writeLines)
+	}
+	if stats := fileBackend.Stats()[Info]; stats.Bytes != 8 || stats.Lines != 2 {
+		t.Errorf("stats not match, expect: 8 bytes 2 lines, actual: %+v", stats)
+	}
+
+	fileBackend.Flush()
+
+	// a failed write counts the accepted bytes but no line.
+	writer.writer = bufio.NewWriterSize(partialWriter{}, 4)
+	fileBackend.Log(Info, []byte("0123456789"))
+	if writer.writeSize != 11 || writer.writeLines != 2 {
+		t.Errorf("counters not match, expect: 11 bytes 2 lines, actual: %v bytes %v lines",
+			writer.writeSize, writer.writeLines)
+	}
+	if stats := fileBackend.Stats()[Info]; stats.Bytes != 11 || stats.Lines != 2 {
+		t.Errorf("stats not match, expect: 11 bytes 2 lines, actual: %+v", stats)
+	}
+	writer.writer.Reset(ioutil.Discard)
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if expect := bytes.Join(chunks, nil); !bytes.Equal(content, expect) {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+}
+
+// lengthPrefixEncoder encodes an entry as a 4 bytes big endian length, the
+// level byte and msg.
+type lengthPrefixEncoder struct{}
+
+func (lengthPrefixEncoder) Encode(level Level, t time.Time, msg []byte) []byte {
+	record := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(record, uint32(1+len(msg)))
+	record[4] = byte(level)
+	return append(record, msg...)
+}
+
+func TestEncoder(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.SetEncoder(lengthPrefixEncoder{})
+	fileBackend.SetEnsureNewline(true)
+	fileBackend.SetTimestampLayout("15:04")
+	messages := [][]byte{[]byte("first"), {}, []byte("with\nnewline\n"), {0x00, 0xff}}
+	for _, message := range messages {
+		fileBackend.Log(Info, message)
+	}
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	for i, message := range messages {
+		if len(content) < 5 {
+			t.Fatalf("frame %v is truncated, remaining: %q", i, content)
+		}
+		length := int(binary.BigEndian.Uint32(content))
+		if length != 1+len(message) || Level(content[4]) != Info {
+			t.Fatalf("frame %v header not match, length: %v, level: %v", i, length, content[4])
+		}
+		if actual := content[5 : 4+length]; !bytes.Equal(actual, message) {
+			t.Errorf("frame %v not match, expect: %q, actual: %q", i, message, actual)
+		}
+		content = content[4+length:]
+	}
+	if len(content) != 0 {
+		t.Errorf("unexpected content after frames: %q", content)
+	}
+}
+
+func TestLogN(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	content := []byte("This is one string.\n")
+	n, err := fileBackend.LogN(Info, content)
+	if err != nil || n != len(content) {
+		t.Errorf("log count not match, expect: %v, actual: %v, err: %v", len(content), n, err)
+	}
+
+	fileBackend.SetEnsureNewline(true)
+	fileBackend.SetTimestampLayout("15:04")
+	n, err = fileBackend.LogN(Info, []byte("no newline"))
+	if expect := len("00:00 no newline\n"); err != nil || n != expect {
+		t.Errorf("log count not match, expect: %v, actual: %v, err: %v", expect, n, err)
+	}
+	fileBackend.SetMinLevel(Warning)
+	if n, err := fileBackend.LogN(Info, content); err != nil || n != 0 {
+		t.Errorf("dropped log should count 0, actual: %v, err: %v", n, err)
+	}
+}
+
+type diskFullWriter struct{}
+
+func (diskFullWriter) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: "test", Err: syscall.ENOSPC}
+}
+
+func TestDiskFullRetry(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	var failedLevels []Level
+	fileBackend.SetOnWriteError(func(level Level, err error) {
+		if !errors.Is(err, syscall.ENOSPC) {
+			t.Errorf("error should be ENOSPC, actual: %v", err)
+		}
+		failedLevels = append(failedLevels, level)
+	})
+
+	fileBackend.writer[Info].writer = bufio.NewWriterSize(diskFullWriter{}, 16)
+	if err := fileBackend.LogE(Info, []byte("This string is longer than the buffer.\n")); err == nil {
+		t.Fatalf("write should fail")
+	}
+	if len(failedLevels) != 1 || failedLevels[0] != Info {
+		t.Fatalf("callback should be called with info, actual: %v", failedLevels)
+	}
+	if err := fileBackend.LogE(Error, []byte("dropped\n")); err != errWritesPaused {
+		t.Errorf("writes should be paused, actual: %v", err)
+	}
+	if dropped := fileBackend.Stats()[Error].Dropped; dropped != 1 {
+		t.Errorf("dropped lines should be 1, actual: %v", dropped)
+	}
+
+	nowTime = nowTime.Add(diskFullRetryInterval)
+	if err := fileBackend.LogE(Info, []byte("recovered\n")); err != nil {
+		t.Fatalf("write should recover, err: %v", err)
+	}
+	fileBackend.Flush()
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if expectContent := "recovered\n"; string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func TestCloseStopsGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		fileBackend := createFileBackend(t)
+		fileBackend.Close()
+	}
+
+	deadline := time.Now().Add(time.Second * 2)
+	for {
+		after := runtime.NumGoroutine()
+		if after <= before {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked, before: %v, after: %v", before, after)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestZeroFlushInterval(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetFlushInterval(0)
+	fileBackend.SetLevelFlushInterval(Debug, time.Millisecond*10)
+	if interval := fileBackend.getFlushInterval(); interval != time.Millisecond*10 {
+		t.Errorf("flush interval should be the positive one, actual: %v", interval)
+	}
+
+	fileBackend.Log(Info, []byte("info\n"))
+	if buffered := fileBackend.writer[Info].writer.Buffered(); buffered != 0 {
+		t.Errorf("INFO should be flushed on write, buffered: %v", buffered)
+	}
+	fileBackend.Log(Debug, []byte("debug\n"))
+	filePath := fileBackend.CurrentFilePath(Debug)
+	deadline := time.Now().Add(time.Second * 2)
+	for {
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", filePath, err)
+		}
+		if string(content) == "debug\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("DEBUG not flushed by the loop")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestIntervalLoopDisabled(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	var calls int32
+	var interval int64
+	reset := make(chan struct{}, 1)
+	fileBackend.loops.Add(1)
+	go fileBackend.intervalLoop(func() {
+		atomic.AddInt32(&calls, 1)
+	}, func() time.Duration {
+		return time.Duration(atomic.LoadInt64(&interval))
+	}, reset)
+
+	time.Sleep(time.Millisecond * 50)
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("zero interval should disable the loop, calls: %d", n)
+	}
+	atomic.StoreInt64(&interval, int64(time.Millisecond))
+	reset <- struct{}{}
+	deadline := time.Now().Add(time.Second * 2)
+	for atomic.LoadInt32(&calls) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("loop not resumed after reset")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	fileBackend.Close()
+}
+
+func TestCloseWaitsLoops(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.SetFlushInterval(time.Millisecond)
+	fileBackend.SetMonitorInterval(time.Millisecond)
+	fileBackend.SetRotateCheckInterval(time.Millisecond)
+	fileBackend.Log(Info, []byte("This is one string.\n"))
+	time.Sleep(time.Millisecond * 10)
+
+	// Close waits the loops, so a loop ignoring done hangs it.
+	closed := make(chan struct{})
+	go func() {
+		fileBackend.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 2):
+		t.Fatalf("background loops not stopped after close")
+	}
+}
+
+func TestSetFlushIntervalTakesEffect(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetFlushInterval(time.Millisecond * 20)
+
+	outputContent := "This is one string."
+	fileBackend.Log(Info, []byte(outputContent))
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	deadline := time.Now().Add(time.Second)
+	for {
+		content, err := ioutil.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", logFilePath, err)
+		}
+		if string(content) == outputContent {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("content should be flushed within new interval, actual: %s", content)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestLogContext(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.LogContext(context.Background(), Info, []byte("without trace\n"))
+	ctx := WithTraceID(context.Background(), "abc123")
+	fileBackend.LogContext(ctx, Info, []byte("with trace\n"))
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	expectContent := "without trace\n[trace=abc123] with trace\n"
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func TestCustomLevel(t *testing.T) {
+	trace := registerTestLevel(t, -1, "TRACE")
+	fileBackend := createFileBackend(t)
+	audit := registerTestLevel(t, 10, "AUDIT")
+
+	outputContent := map[Level]string{
+		trace: "This is a trace string.",
+		Debug: "This is a debug string.",
+		audit: "This is an audit string.",
+	}
+	for level, content := range outputContent {
+		if err := fileBackend.LogE(level, []byte(content)); err != nil {
+			t.Fatalf("log %s failed, err: %v", levelNames[level], err)
+		}
+	}
+	fileBackend.Close()
+
+	for level, expectContent := range outputContent {
+		logFilePath := path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)
+		content, err := ioutil.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("read %s log failed, err: %v", levelNames[level], err)
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s log not match, expect: %s, write: %s",
+				levelNames[level], expectContent, content)
+		}
+	}
+}
+
+func TestRotateRenameFailed(t *testing.T) {
+	fileBackend := createFileBackend(t)
+
+	// current files fit in the limit of name length, rotated ones do not,
+	// which makes the rename fail.
+	if err := fileBackend.SetFilePrefix(strings.Repeat("p", 243)); err != nil {
+		t.Fatalf("set file prefix failed, err: %v", err)
+	}
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.SetRotateFile(true, 0)
+	fileBackend.Log(Info, []byte("before rotate\n"))
+
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.doRotateByHour()
+
+	fileBackend.Log(Info, []byte("after rotate\n"))
+	logFilePath := fileBackend.writer[Info].filePath
+	fileBackend.Close()
+
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	expectContent := "before rotate\nafter rotate\n"
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func logFromHelper(fileBackend *FileBackend, content string) int {
+	_, _, line, _ := runtime.Caller(0)
+	fileBackend.Log(Info, []byte(content))
+	return line + 1
+}
+
+func TestIncludeCaller(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.SetIncludeCaller(true)
+
+	helperLine := logFromHelper(fileBackend, "from helper\n")
+	fileBackend.SetCallerSkip(1)
+	_, _, callerLine, _ := runtime.Caller(0)
+	logFromHelper(fileBackend, "from caller\n")
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	expectContent := fmt.Sprintf("filebackend_test.go:%d from helper\n", helperLine) +
+		fmt.Sprintf("filebackend_test.go:%d from caller\n", callerLine+1)
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func TestLogStringAndLogf(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.LogString(Info, "from string\n")
+	fileBackend.Logf(Info, "from %s", "format")
+	fileBackend.Logf(Info, "with newline %d\n", 1)
+	fileBackend.SetMinLevel(Warning)
+	fileBackend.Logf(Info, "disabled")
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	expectContent := "from string\nfrom format\nwith newline 1\n"
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func TestIncludeSequence(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.SetIncludeSequence(true)
+	for i := 0; i < 20; i++ {
+		fileBackend.Log(Level(i%len(levels())), []byte("This is one string.\n"))
+	}
+	fileBackend.Close()
+
+	var sequences []int
+	for _, level := range levels() {
+		logFilePath := path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)
+		content, err := ioutil.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", logFilePath, err)
+		}
+		last := 0
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			var sequence int
+			if _, err := fmt.Sscanf(line, "%d ", &sequence); err != nil {
+				t.Fatalf("parse sequence of %q failed, err: %v", line, err)
+			}
+			if sequence <= last {
+				t.Errorf("sequence should increase, last: %v, actual: %v", last, sequence)
+			}
+			last = sequence
+			sequences = append(sequences, sequence)
+		}
+	}
+	sort.Ints(sequences)
+	for i, sequence := range sequences {
+		if sequence != i+1 {
+			t.Fatalf("sequence not match, expect: %v, actual: %v", i+1, sequence)
+		}
+	}
+}
+
+func TestTryLog(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	unlocked := make(chan struct{})
+	go func() {
+		fileBackend.mutex.Lock()
+		close(locked)
+		<-release
+		fileBackend.mutex.Unlock()
+		close(unlocked)
+	}()
+	<-locked
+	for i := 0; i < 3; i++ {
+		if fileBackend.TryLog(Info, []byte("contended\n")) {
+			t.Errorf("try log should fail under contention")
+		}
+	}
+	close(release)
+	<-unlocked
+
+	if !fileBackend.TryLog(Info, []byte("uncontended\n")) {
+		t.Errorf("try log should succeed without contention")
+	}
+	stats := fileBackend.Stats()[Info]
+	if stats.Dropped != 3 || stats.Lines != 1 {
+		t.Errorf("stats not match, expect: 3 dropped 1 line, actual: %+v", stats)
+	}
+}
+
+func TestLogBatch(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.SetIncludeCaller(true)
+	_, _, callerLine, _ := runtime.Caller(0)
+	fileBackend.LogBatch(Info, [][]byte{[]byte("line 0\n"), []byte("line 1\n")})
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	expectContent := fmt.Sprintf("filebackend_test.go:%d line 0\nfilebackend_test.go:%d line 1\n",
+		callerLine+1, callerLine+1)
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func TestEnsureNewline(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	fileBackend.SetEnsureNewline(true)
+	for _, content := range []string{"zero", "one\n", "multiple\n\n\n", ""} {
+		fileBackend.Log(Info, []byte(content))
+	}
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	expectContent := "zero\none\nmultiple\n\n"
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %q, write: %q", expectContent, content)
+	}
+}
+
+func TestMaxLineBytes(t *testing.T) {
+	cases := []struct {
+		content string
+		expect  string
+	}{
+		{"short\n", "short\n"},
+		{"exactly10!\n", "exactly10!\n"},
+		{"longer than ten\n", "longer tha" + truncatedMarker + "\n"},
+		{"no newline at all", "no newline" + truncatedMarker},
+		// "é" is 2 bytes and "世" is 3 bytes.
+		{"12345678é\n", "12345678é\n"},
+		{"12345678éé\n", "12345678é" + truncatedMarker + "\n"},
+		{"123456789é\n", "123456789" + truncatedMarker + "\n"},
+		{"12345678世界\n", "12345678" + truncatedMarker + "\n"},
+	}
+	fileBackend := createFileBackend(t)
+	fileBackend.SetMaxLineBytes(10)
+	var expectContent string
+	for _, c := range cases {
+		if actual := string(truncateLine([]byte(c.content), 10)); actual != c.expect {
+			t.Errorf("truncated line not match, expect: %q, actual: %q", c.expect, actual)
+		}
+		fileBackend.Log(Info, []byte(c.content))
+		expectContent += c.expect
+	}
+	fileBackend.Close()
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %q, write: %q", expectContent, content)
+	}
+}
+
+func TestCombinedFile(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.SetRotateFile(true, 0)
+	if err := fileBackend.SetCombinedFile(true); err != nil {
+		t.Fatalf("set combined file failed, err: %v", err)
+	}
+
+	fileBackend.Log(Error, []byte("first\n"))
+	fileBackend.Log(Debug, []byte("second\n"))
+	fileBackend.Log(Warning, []byte("third\n"))
+	fileBackend.Flush()
+
+	expectContent := "ERROR first\nDEBUG second\nWARNING third\n"
+	combinedFilePath := path.Join(fileBackend.dir, combinedFileName+logFileSuffix)
+	content, err := ioutil.ReadFile(combinedFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", combinedFilePath, err)
+	}
+	if string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+	for level := range levelNames {
+		logFilePath := path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)
+		content, err := ioutil.ReadFile(logFilePath)
+		if err == nil && len(content) != 0 {
+			t.Errorf("%s should receive nothing, write: %s", levelNames[level], content)
+		}
+	}
+
+	// all levels share one writer, so rotation creates one file only.
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.doRotateByHour()
+	files, err := ioutil.ReadDir(fileBackend.dir)
+	if err != nil {
+		t.Fatalf("read temporary directory failed, err: %v", err)
+	}
+	rotatedCount := 0
+	for _, file := range files {
+		if rotatedFilenamePattern.FindString(file.Name()) == file.Name() {
+			rotatedCount++
+			if !strings.HasPrefix(file.Name(), combinedFileName) {
+				t.Errorf("invalid rotated file: %v", file.Name())
+			}
+		}
+	}
+	if rotatedCount != 1 {
+		t.Errorf("count of rotated file should be 1, actual: %v", rotatedCount)
+	}
+	if !fileBackend.shouldDelete(combinedFileName+logFileSuffix+"."+
+		nowTime.Add(-time.Hour*2).Format(datetimeSuffixLayout), 1) {
+		t.Errorf("old combined file should be deleted")
+	}
+}
+
+func TestLevelRouting(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	err := fileBackend.SetLevelRouting(map[Level]string{
+		Warning: "problems",
+		Error:   "problems",
+	})
+	if err != nil {
+		t.Fatalf("set level routing failed, err: %v", err)
+	}
+	if fileBackend.writer[Warning] != fileBackend.writer[Error] {
+		t.Errorf("warning and error should share one writer")
+	}
+	if count := len(fileBackend.writers()); count != len(levels())-1 {
+		t.Errorf("count of writers should be %v, actual: %v", len(levels())-1, count)
+	}
+	fileBackend.Log(Warning, []byte("This is a warning string.\n"))
+	fileBackend.Log(Error, []byte("This is a error string.\n"))
+	fileBackend.Log(Info, []byte("This is a info string.\n"))
+	fileBackend.Close()
+
+	expects := map[string]string{
+		"problems.log": "WARNING This is a warning string.\nERROR This is a error string.\n",
+		"INFO.log":     "This is a info string.\n",
+	}
+	for name, expectContent := range expects {
+		logFilePath := path.Join(fileBackend.dir, name)
+		content, err := ioutil.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", logFilePath, err)
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s not match, expect: %s, write: %s", name, expectContent, content)
+		}
+	}
+	if !fileBackend.rotatedFilenamePattern.MatchString("problems.log.2019071001") {
+		t.Errorf("rotated file of routed level should match pattern")
+	}
+	if err := fileBackend.SetLevelRouting(map[Level]string{Error: "a.b"}); err == nil {
+		t.Errorf("invalid file name should return error")
+	}
+}
+
+func TestTruncateOnOpen(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "fileBackend_test")
+	if err != nil {
+		t.Fatalf("create temporary directoey failed, err: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	filePath := path.Join(tempDir, levelNames[Info]+logFileSuffix)
+	if err := ioutil.WriteFile(filePath, []byte("old content\n"), 0644); err != nil {
+		t.Fatalf("write %s failed, err: %v", filePath, err)
+	}
+
+	fileBackend, err := NewFileBackend(tempDir)
+	if err != nil {
+		t.Fatalf("create file backend failed, err: %v", err)
+	}
+	defer fileBackend.Close()
+	if err := fileBackend.SetTruncateOnOpen(true); err != nil {
+		t.Fatalf("set truncate on open failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("new content\n"))
+	// reopens append.
+	if err := fileBackend.ReopenFiles(); err != nil {
+		t.Fatalf("reopen files failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("after reopen\n"))
+	fileBackend.Flush()
+
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if expect := "new content\nafter reopen\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+
+	// the level turned on again keeps its earlier lines.
+	if err := fileBackend.SetMinLevel(Warning); err != nil {
+		t.Fatalf("set min level failed, err: %v", err)
+	}
+	if err := fileBackend.SetMinLevel(Debug); err != nil {
+		t.Fatalf("set min level failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("after min level\n"))
+	fileBackend.Flush()
+	content, err = ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if expect := "new content\nafter reopen\nafter min level\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+}
+
+type countingWriter struct {
+	writer io.Writer
+	count  *int64
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	atomic.AddInt64(w.count, int64(n))
+	return n, err
+}
+
+func TestFlushOnLevel(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	// flush by the test only.
+	fileBackend.SetFlushInterval(time.Hour)
+
+	fileBackend.Log(Info, []byte("info\n"))
+	fileBackend.Log(Error, []byte("error\n"))
+	if buffered := fileBackend.writer[Info].writer.Buffered(); buffered == 0 {
+		t.Errorf("INFO should not be flushed by ERROR by default")
+	}
+	fileBackend.SetFlushOnLevel(Error)
+	fileBackend.Log(Error, []byte("error\n"))
+	for _, level := range []Level{Info, Error} {
+		if buffered := fileBackend.writer[level].writer.Buffered(); buffered != 0 {
+			t.Errorf("%s should be flushed, buffered: %v", levelNames[level], buffered)
+		}
+	}
+}
+
+func TestExitOnFatal(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	var codes []int
+	fileBackend.exit = func(code int) {
+		codes = append(codes, code)
+	}
+
+	fileBackend.Log(Fatal, []byte("before enabled\n"))
+	fileBackend.SetExitOnFatal(true)
+	fileBackend.Log(Error, []byte("error\n"))
+	fileBackend.Log(Fatal, []byte("fatal\n"))
+	if len(codes) != 1 || codes[0] != 1 {
+		t.Errorf("exit should be called once with 1, actual: %v", codes)
+	}
+
+	// flushed before exit.
+	logFilePath := path.Join(fileBackend.dir, levelNames[Fatal]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if expect := "before enabled\nfatal\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+
+	// the flush level does not stop Fatal from exiting.
+	fileBackend.SetFlushOnLevel(Fatal + 1)
+	fileBackend.Log(Fatal, []byte("above flush level\n"))
+	if len(codes) != 2 {
+		t.Errorf("exit should be called again, actual: %v", codes)
+	}
+	content, err = ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if expect := "before enabled\nfatal\nabove flush level\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+}
+
+func TestWriterWrapper(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	counts := make(map[Level]*int64)
+	for _, level := range levels() {
+		counts[level] = new(int64)
+	}
+	err := fileBackend.SetWriterWrapper(func(level Level, w io.Writer) io.Writer {
+		return countingWriter{writer: w, count: counts[level]}
+	})
+	if err != nil {
+		t.Fatalf("set writer wrapper failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("0123456789\n"))
+	fileBackend.Log(Info, []byte("0123456789\n"))
+	fileBackend.Log(Error, []byte("error\n"))
+	fileBackend.Flush()
+
+	expects := map[Level]int64{Debug: 0, Info: 22, Warning: 0, Error: 6, Fatal: 0}
+	for level, expect := range expects {
+		if actual := atomic.LoadInt64(counts[level]); actual != expect {
+			t.Errorf("%s count not match, expect: %v, actual: %v", levelNames[level], expect, actual)
+		}
+	}
+}
+
+type blockingWriter struct {
+	io.Writer
+	release chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.Writer.Write(p)
+}
+
+type slowWriter struct {
+	io.Writer
+	delay time.Duration
+}
+
+func (w slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.Writer.Write(p)
+}
+
+func TestOnSlowIO(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	// flush by the test only.
+	fileBackend.SetFlushInterval(time.Hour)
+
+	var ops []string
+	fileBackend.SetOnSlowIO(50*time.Millisecond, func(op string, d time.Duration) {
+		if d <= 50*time.Millisecond {
+			t.Errorf("%s of %v should not be reported", op, d)
+		}
+		ops = append(ops, op)
+	})
+	fileBackend.Log(Error, []byte("fast\n"))
+	fileBackend.Flush()
+	if len(ops) != 0 {
+		t.Errorf("fast flush should not be reported, actual: %v", ops)
+	}
+
+	err := fileBackend.SetWriterWrapper(func(level Level, w io.Writer) io.Writer {
+		return slowWriter{Writer: w, delay: 100 * time.Millisecond}
+	})
+	if err != nil {
+		t.Fatalf("set writer wrapper failed, err: %v", err)
+	}
+	writer := fileBackend.writer[Info]
+	writer.syncFile = func(file *os.File) error {
+		time.Sleep(100 * time.Millisecond)
+		return file.Sync()
+	}
+	fileBackend.Log(Info, []byte("slow\n"))
+	if err := fileBackend.FlushAndSync(); err != nil {
+		t.Fatalf("flush and sync failed, err: %v", err)
+	}
+	if len(ops) != 2 || ops[0] != "flush" || ops[1] != "sync" {
+		t.Errorf("reported operations not match, expect: [flush sync], actual: %v", ops)
+	}
+}
+
+func TestCloseWithTimeout(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	release := make(chan struct{})
+	defer close(release)
+	err := fileBackend.SetWriterWrapper(func(level Level, w io.Writer) io.Writer {
+		return blockingWriter{Writer: w, release: release}
+	})
+	if err != nil {
+		t.Fatalf("set writer wrapper failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("blocked\n"))
+
+	start := time.Now()
+	if err := fileBackend.CloseWithTimeout(50 * time.Millisecond); err == nil {
+		t.Errorf("close blocked backend should time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("close should return after the timeout, elapsed: %v", elapsed)
+	}
+}
+
+func TestFallbackWriter(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetLazyFileCreation(true)
+	// a directory can not be opened as the file of a level.
+	if err := os.MkdirAll(path.Join(fileBackend.dir, levelNames[Error]+logFileSuffix), defaultDirMode); err != nil {
+		t.Fatalf("create directory failed, err: %v", err)
+	}
+	fileBackend.SetFallbackWriter(nil)
+	if err := fileBackend.LogE(Error, []byte("lost\n")); err == nil {
+		t.Errorf("log without fallback should fail")
+	}
+
+	var fallback bytes.Buffer
+	fileBackend.SetFallbackWriter(&fallback)
+	fileBackend.SetTimestampLayout("15:04")
+	nowTime := time.Date(2020, 1, 2, 3, 4, 0, 0, time.Local)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.Log(Error, []byte("to fallback\n"))
+	fileBackend.SetTimestampLayout("")
+	fileBackend.Log(Info, []byte("to file\n"))
+	fileBackend.Flush()
+
+	if stats := fileBackend.Stats()[Error]; stats.Lines != 1 {
+		t.Errorf("fallback lines not match, expect: 1, actual: %d", stats.Lines)
+	}
+	if expect := "ERROR 03:04 to fallback\n"; fallback.String() != expect {
+		t.Errorf("fallback content not match, expect: %q, actual: %q", expect, fallback.String())
+	}
+	filePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if expect := "to file\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+}
+
+func TestMirrorThreshold(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	if err := fileBackend.SetMirrorThreshold(Warning, "alerts.log"); err != nil {
+		t.Fatalf("set mirror threshold failed, err: %v", err)
+	}
+	for _, level := range levels() {
+		fileBackend.Log(level, []byte(levelNames[level]+"\n"))
+	}
+	fileBackend.Close()
+
+	mirrorPath := path.Join(fileBackend.dir, "alerts.log")
+	content, err := ioutil.ReadFile(mirrorPath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", mirrorPath, err)
+	}
+	if expect := "WARNING\nERROR\nFATAL\n"; string(content) != expect {
+		t.Errorf("mirror content not match, expect: %q, actual: %q", expect, content)
+	}
+	filePath := path.Join(fileBackend.dir, levelNames[Error]+logFileSuffix)
+	content, err = ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if expect := "ERROR\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+}
+
+func TestLazyFileCreation(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	fileBackend.SetLazyFileCreation(true)
+	for _, level := range levels() {
+		if _, err := os.Stat(path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)); !os.IsNotExist(err) {
+			t.Errorf("file of %s should not exist, err: %v", levelNames[level], err)
+		}
+	}
+	fileBackend.Log(Warning, []byte("warning\n"))
+	fileBackend.Flush()
+	for _, level := range levels() {
+		filepath := path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)
+		_, err := os.Stat(filepath)
+		if level == Warning && err != nil {
+			t.Errorf("stat %s failed, err: %v", filepath, err)
+		} else if level != Warning && !os.IsNotExist(err) {
+			t.Errorf("file of %s should not exist, err: %v", levelNames[level], err)
+		}
+	}
+	if err := fileBackend.SetMinLevel(Info); err != nil {
+		t.Fatalf("set min level failed, err: %v", err)
+	}
+	if _, err := os.Stat(path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)); !os.IsNotExist(err) {
+		t.Errorf("file of INFO should not exist, err: %v", err)
+	}
+}
+
+func TestCurrentFilePath(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	expect := path.Join(fileBackend.dir, levelNames[Warning]+logFileSuffix)
+	if actual := fileBackend.CurrentFilePath(Warning); actual != expect {
+		t.Errorf("file path not match, expect: %v, actual: %v", expect, actual)
+	}
+	if err := fileBackend.SetCombinedFile(true); err != nil {
+		t.Fatalf("set combined file failed, err: %v", err)
+	}
+	expect = path.Join(fileBackend.dir, combinedFileName+logFileSuffix)
+	if actual := fileBackend.CurrentFilePath(Warning); actual != expect {
+		t.Errorf("file path not match, expect: %v, actual: %v", expect, actual)
+	}
+	if actual := fileBackend.CurrentFilePath(Level(100)); actual != "" {
+		t.Errorf("file path of invalid level should be empty, actual: %v", actual)
+	}
+}
+
+func TestStats(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	for i := 0; i < 3; i++ {
+		fileBackend.Log(Info, []byte("0123456789"))
+	}
+	fileBackend.Log(Error, []byte("01234"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fileBackend.Log(Debug, []byte("0"))
+				fileBackend.Stats()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := fileBackend.Stats()
+	expect := map[Level]BackendStats{
+		Debug: {Lines: 400, Bytes: 400},
+		Info:  {Lines: 3, Bytes: 30},
+		Error: {Lines: 1, Bytes: 5},
+	}
+	if len(stats) != len(expect) {
+		t.Errorf("stats not match, expect: %v, actual: %v", expect, stats)
+	}
+	for level, expectStats := range expect {
+		if stats[level] != expectStats {
+			t.Errorf("%s stats not match, expect: %v, actual: %v",
+				levelNames[level], expectStats, stats[level])
+		}
+	}
+}
+
+func TestSyncEveryWrite(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetSyncEveryWrite(true)
+
+	outputContent := "This is one string."
+	if err := fileBackend.LogE(Info, []byte(outputContent)); err != nil {
+		t.Fatalf("log failed, err: %v", err)
+	}
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if string(content) != outputContent {
+		t.Errorf("content should be written without flush, expect: %s, write: %s",
+			outputContent, content)
+	}
+}
+
+func TestMaxTotalBytes(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	nowTime := time.Date(2019, 7, 10, 10, 13, 14, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.SetRotateFile(true, 0)
+	fileBackend.SetMaxTotalBytes(250)
+
+	// five rotated files of 100 bytes, from 5 hours ago to 1 hour ago.
+	var rotatedFiles []string
+	for i := 5; i >= 1; i-- {
+		level := Level(i % len(levels()))
+		name := levelNames[level] + logFileSuffix + "." +
+			nowTime.Add(-time.Hour*time.Duration(i)).Format(datetimeSuffixLayout)
+		rotatedFiles = append(rotatedFiles, name)
+		content := []byte(strings.Repeat("x", 100))
+		if err := ioutil.WriteFile(path.Join(fileBackend.dir, name), content, 0644); err != nil {
+			t.Fatalf("write %s failed, err: %v", name, err)
+		}
+	}
+	fileBackend.doRotateByHour()
+
+	for i, name := range rotatedFiles {
+		_, err := os.Stat(path.Join(fileBackend.dir, name))
+		if i < 3 && !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, err: %v", name, err)
+		}
+		if i >= 3 && err != nil {
+			t.Errorf("%s should be kept, err: %v", name, err)
+		}
+	}
+}
+
+func TestMaxTotalBytesIndexed(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetMaxTotalBytes(250)
+
+	// files rotated by size, the freed index 1 is taken by the newest one.
+	now := time.Now()
+	var rotatedFiles []string
+	for i, index := range []int{2, 3, 4, 1} {
+		name := levelNames[Info] + logFileSuffix + "." + strconv.Itoa(index)
+		rotatedFiles = append(rotatedFiles, name)
+		filePath := path.Join(fileBackend.dir, name)
+		content := []byte(strings.Repeat("x", 100))
+		if err := ioutil.WriteFile(filePath, content, 0644); err != nil {
+			t.Fatalf("write %s failed, err: %v", name, err)
+		}
+		modTime := now.Add(time.Minute * time.Duration(i-4))
+		if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+			t.Fatalf("change time of %s failed, err: %v", name, err)
+		}
+	}
+	// retention runs without hourly rotation.
+	fileBackend.doRotateByHour()
+
+	for i, name := range rotatedFiles {
+		_, err := os.Stat(path.Join(fileBackend.dir, name))
+		if i < 2 && !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, err: %v", name, err)
+		}
+		if i >= 2 && err != nil {
+			t.Errorf("%s should be kept, err: %v", name, err)
+		}
+	}
+}
+
+// keepLastPolicy keeps the newest keep rotated files of each level.
+type keepLastPolicy struct {
+	dir  string
+	keep int
+}
+
+func (p keepLastPolicy) ShouldDelete(name string, info os.FileInfo, now time.Time) bool {
+	prefix := name[:strings.Index(name, logFileSuffix)+len(logFileSuffix)]
+	names, err := filepath.Glob(path.Join(p.dir, prefix+".*"))
+	if err != nil {
+		return false
+	}
+	sort.Strings(names)
+	for i, other := range names {
+		if path.Base(other) == name {
+			return i < len(names)-p.keep
+		}
+	}
+	return false
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	// rotate by the test only.
+	fileBackend.SetRotateCheckInterval(time.Hour)
+
+	nowTime := time.Date(2019, 7, 10, 10, 13, 14, 0, time.UTC)
+	fileBackend.SetClock(func() time.Time {
+		return nowTime
+	})
+	fileBackend.SetRotateFile(true, 1)
+	fileBackend.SetRetentionPolicy(keepLastPolicy{dir: fileBackend.dir, keep: 2})
+
+	var rotatedFiles []string
+	for i := 4; i >= 1; i-- {
+		name := levelNames[Info] + logFileSuffix + "." +
+			nowTime.Add(-time.Hour*time.Duration(i)).Format(datetimeSuffixLayout)
+		rotatedFiles = append(rotatedFiles, name)
+		if err := ioutil.WriteFile(path.Join(fileBackend.dir, name), []byte("x\n"), 0644); err != nil {
+			t.Fatalf("write %s failed, err: %v", name, err)
+		}
+	}
+	fileBackend.doRotateByHour()
+
+	for i, name := range rotatedFiles {
+		_, err := os.Stat(path.Join(fileBackend.dir, name))
+		if i < 2 && !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, err: %v", name, err)
+		}
+		// kept although older than keepHours.
+		if i >= 2 && err != nil {
+			t.Errorf("%s should be kept, err: %v", name, err)
+		}
+	}
+}
+
+func TestAdversarialRotatedFilenames(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	nowTime := time.Date(2019, 7, 10, 10, 13, 14, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.SetRotateFile(true, 1)
+
+	oldSuffix := nowTime.Add(-time.Hour * 5).Format(datetimeSuffixLayout)
+	adversarialNames := []string{
+		"DEBUG.log." + oldSuffix + ".extra",
+		"DEBUG.log." + oldSuffix + ".gz.bak",
+		"xDEBUG.log." + oldSuffix,
+		"DEBUG.log." + oldSuffix[:len(oldSuffix)-1],
+		"DEBUG.log." + oldSuffix + "0",
+		"app.DEBUG.log." + oldSuffix,
+	}
+	for _, name := range adversarialNames {
+		if rotatedFilenamePattern.MatchString(name) {
+			t.Errorf("%s should not match rotated pattern", name)
+		}
+		if fileBackend.shouldDelete(name, 1) {
+			t.Errorf("%s should not be deleted", name)
+		}
+		if err := ioutil.WriteFile(path.Join(fileBackend.dir, name), nil, 0644); err != nil {
+			t.Fatalf("write %s failed, err: %v", name, err)
+		}
+	}
+	rotatedName := "DEBUG.log." + oldSuffix
+	if err := ioutil.WriteFile(path.Join(fileBackend.dir, rotatedName), nil, 0644); err != nil {
+		t.Fatalf("write %s failed, err: %v", rotatedName, err)
+	}
+	fileBackend.doRotateByHour()
+
+	for _, name := range adversarialNames {
+		if _, err := os.Stat(path.Join(fileBackend.dir, name)); err != nil {
+			t.Errorf("%s should be kept, err: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(path.Join(fileBackend.dir, rotatedName)); !os.IsNotExist(err) {
+		t.Errorf("%s should be removed, err: %v", rotatedName, err)
+	}
+}
+
+func TestSetFilePrefix(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "fileBackend_test")
+	if err != nil {
+		t.Fatalf("create temporary directoey failed, err: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	nowTime := time.Date(2019, 7, 10, 10, 13, 14, 0, time.UTC)
+	prefixes := []string{"app", "access"}
+	backends := make([]*FileBackend, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		fileBackend, err := NewFileBackend(tempDir)
+		if err != nil {
+			t.Fatalf("create file backend failed, err: %v", err)
+		}
+		defer fileBackend.Close()
+		fileBackend.getNowTime = func() time.Time {
+			return nowTime
+		}
+		if err := fileBackend.SetFilePrefix(prefix); err != nil {
+			t.Fatalf("set file prefix failed, err: %v", err)
+		}
+		fileBackend.SetRotateFile(true, 1)
+		fileBackend.Log(Info, []byte("This is a "+prefix+" string."))
+		fileBackend.Flush()
+		backends = append(backends, fileBackend)
+	}
+
+	for _, prefix := range prefixes {
+		logFilePath := path.Join(tempDir, prefix+"."+levelNames[Info]+logFileSuffix)
+		content, err := ioutil.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", logFilePath, err)
+		}
+		if expectContent := "This is a " + prefix + " string."; string(content) != expectContent {
+			t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+		}
+	}
+
+	// retention of one backend leaves files of the other alone.
+	oldSuffix := nowTime.Add(-time.Hour * 5).Format(datetimeSuffixLayout)
+	for _, prefix := range prefixes {
+		name := prefix + "." + levelNames[Info] + logFileSuffix + "." + oldSuffix
+		if err := ioutil.WriteFile(path.Join(tempDir, name), nil, 0644); err != nil {
+			t.Fatalf("write %s failed, err: %v", name, err)
+		}
+	}
+	backends[0].doRotateByHour()
+	if _, err := os.Stat(path.Join(tempDir, "app.INFO.log."+oldSuffix)); !os.IsNotExist(err) {
+		t.Errorf("old app file should be removed, err: %v", err)
+	}
+	if _, err := os.Stat(path.Join(tempDir, "access.INFO.log."+oldSuffix)); err != nil {
+		t.Errorf("old access file should be kept, err: %v", err)
+	}
+}
+
+func TestLevelWriter(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	logger := log.New(fileBackend.LevelWriter(Error), "", 0)
+	logger.Print("This is a error string.")
+	fileBackend.Close()
+
+	for level := range levelNames {
+		logFilePath := path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)
+		content, err := ioutil.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", logFilePath, err)
+		}
+		expectContent := ""
+		if level == Error {
+			expectContent = "This is a error string.\n"
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s log not match, expect: %s, write: %s",
+				levelNames[level], expectContent, content)
+		}
+	}
+}
+
+func TestFlushSkipsIdleWriters(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	syncCount := 0
+	for _, writer := range fileBackend.writers() {
+		writer.syncFile = func(file *os.File) error {
+			syncCount++
+			return file.Sync()
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		fileBackend.FlushAndSync()
+	}
+	if syncCount != 0 {
+		t.Errorf("idle backend should not sync, actual: %v", syncCount)
+	}
+
+	fileBackend.Log(Info, []byte("This is one string."))
+	fileBackend.FlushAndSync()
+	fileBackend.FlushAndSync()
+	if syncCount != 1 {
+		t.Errorf("sync count should be 1, actual: %v", syncCount)
+	}
+}
+
+func TestFlushLevel(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	// flush by the test only.
+	fileBackend.SetFlushInterval(time.Hour)
+
+	fileBackend.Log(Info, []byte("info\n"))
+	fileBackend.Log(Error, []byte("error\n"))
+	if err := fileBackend.FlushLevel(Error); err != nil {
+		t.Fatalf("flush level failed, err: %v", err)
+	}
+	if buffered := fileBackend.writer[Error].writer.Buffered(); buffered != 0 {
+		t.Errorf("ERROR should be flushed, buffered: %v", buffered)
+	}
+	if buffered := fileBackend.writer[Info].writer.Buffered(); buffered != len("info\n") {
+		t.Errorf("INFO should not be flushed, buffered: %v", buffered)
+	}
+	if err := fileBackend.FlushLevel(Level(100)); err != nil {
+		t.Errorf("flush level without file should do nothing, err: %v", err)
+	}
+}
+
+func TestWriteCurrentTo(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	fileBackend.Log(Info, []byte("first\n"))
+	fileBackend.Log(Info, []byte("second\n"))
+	fileBackend.Log(Error, []byte("error\n"))
+	var buffer bytes.Buffer
+	n, err := fileBackend.WriteCurrentTo(Info, &buffer)
+	if err != nil {
+		t.Fatalf("write current file failed, err: %v", err)
+	}
+	if expect := "first\nsecond\n"; buffer.String() != expect || n != int64(len(expect)) {
+		t.Errorf("content not match, expect: %q, actual: %q, count: %v", expect, buffer.String(), n)
+	}
+	if _, err := fileBackend.WriteCurrentTo(Level(100), &buffer); err == nil {
+		t.Errorf("write current file of invalid level should fail")
+	}
+}
+
+func TestFlushWithoutSync(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	syncCount := 0
+	for _, writer := range fileBackend.writers() {
+		writer.syncFile = func(file *os.File) error {
+			syncCount++
+			return file.Sync()
+		}
+	}
+
+	outputContent := "This is one string."
+	fileBackend.Log(Info, []byte(outputContent))
+	fileBackend.Flush()
+	if syncCount != 0 {
+		t.Errorf("flush should not sync, actual: %v", syncCount)
+	}
+	logFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", logFilePath, err)
+	}
+	if string(content) != outputContent {
+		t.Errorf("content not match, expect: %s, write: %s", outputContent, content)
+	}
+
+	if err := fileBackend.Sync(); err != nil {
+		t.Fatalf("sync failed, err: %v", err)
+	}
+	if err := fileBackend.Sync(); err != nil {
+		t.Fatalf("sync failed, err: %v", err)
+	}
+	if syncCount != 1 {
+		t.Errorf("sync count should be 1, actual: %v", syncCount)
+	}
+}
+
+func TestSetLevelFlushInterval(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetFlushInterval(time.Hour)
+	fileBackend.SetLevelFlushInterval(Debug, time.Millisecond*20)
+	fileBackend.SetLevelFlushInterval(Error, 0)
+
+	outputContent := "This is one string."
+	readContent := func(level Level) string {
+		logFilePath := path.Join(fileBackend.dir, levelNames[level]+logFileSuffix)
+		content, err := ioutil.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", logFilePath, err)
+		}
+		return string(content)
+	}
+	for _, level := range []Level{Debug, Info, Error} {
+		fileBackend.Log(level, []byte(outputContent))
+	}
+
+	if content := readContent(Error); content != outputContent {
+		t.Errorf("error should be flushed immediately, actual: %s", content)
+	}
+	deadline := time.Now().Add(time.Second)
+	for readContent(Debug) != outputContent {
+		if time.Now().After(deadline) {
+			t.Fatalf("debug should be flushed within its interval")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if content := readContent(Info); content != "" {
+		t.Errorf("info should follow the global interval, actual: %s", content)
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	// rotate by the test only.
+	fileBackend.SetRotateCheckInterval(time.Hour)
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.SetClock(func() time.Time {
+		return nowTime
+	})
+	if err := fileBackend.SetRotateFileE(true, 0); err != nil {
+		t.Fatalf("set rotate file failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("before rotate\n"))
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.doRotateByHour()
+
+	rotatedFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix+".2019071002")
+	content, err := ioutil.ReadFile(rotatedFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", rotatedFilePath, err)
+	}
+	if expectContent := "before rotate\n"; string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func TestListRotatedFiles(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	names := []string{
+		"INFO.log.2019071001",
+		"INFO.log.2019071003.gz",
+		"INFO.log.2019071002",
+		"INFO.log.1",
+		"ERROR.log.2019071004",
+		"other.INFO.log.2019071005",
+	}
+	for _, name := range names {
+		filePath := path.Join(fileBackend.dir, name)
+		if err := ioutil.WriteFile(filePath, nil, defaultFileMode); err != nil {
+			t.Fatalf("write %s failed, err: %v", filePath, err)
+		}
+	}
+
+	files, err := fileBackend.ListRotatedFiles(Info)
+	if err != nil {
+		t.Fatalf("list rotated files failed, err: %v", err)
+	}
+	expects := []string{
+		path.Join(fileBackend.dir, "INFO.log.2019071003.gz"),
+		path.Join(fileBackend.dir, "INFO.log.2019071002"),
+		path.Join(fileBackend.dir, "INFO.log.2019071001"),
+	}
+	if strings.Join(files, ",") != strings.Join(expects, ",") {
+		t.Errorf("rotated files not match, expect: %v, actual: %v", expects, files)
+	}
+	if _, err := fileBackend.ListRotatedFiles(Level(100)); err == nil {
+		t.Errorf("invalid level should return error")
+	}
+}
+
+func TestOnRotate(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	if err := fileBackend.SetLevelRouting(map[Level]string{Warning: "problems", Error: "problems"}); err != nil {
+		t.Fatalf("set level routing failed, err: %v", err)
+	}
+	// rotate by the test only.
+	fileBackend.SetRotateCheckInterval(time.Hour)
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	rotatedPaths := make(map[Level]string)
+	fileBackend.SetOnRotate(func(level Level, rotatedPath string) {
+		// the backend is not locked in the callback.
+		fileBackend.Log(Info, []byte("rotated\n"))
+		rotatedPaths[level] = rotatedPath
+	})
+	fileBackend.SetRotateFile(true, 0)
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.doRotateByHour()
+
+	expects := map[Level]string{
+		Debug:   path.Join(fileBackend.dir, "DEBUG.log.2019071002"),
+		Info:    path.Join(fileBackend.dir, "INFO.log.2019071002"),
+		Warning: path.Join(fileBackend.dir, "problems.log.2019071002"),
+		Fatal:   path.Join(fileBackend.dir, "FATAL.log.2019071002"),
+	}
+	if len(rotatedPaths) != len(expects) {
+		t.Errorf("rotated paths not match, expect: %v, actual: %v", expects, rotatedPaths)
+	}
+	for level, expect := range expects {
+		if rotatedPaths[level] != expect {
+			t.Errorf("rotated path of %v not match, expect: %v, actual: %v", level, expect, rotatedPaths[level])
+		}
+	}
+}
+
+func TestSetTimeZone(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	// rotate by the test only.
+	fileBackend.SetRotateCheckInterval(time.Hour)
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.SetClock(func() time.Time {
+		return nowTime
+	})
+	fileBackend.SetTimeZone(time.FixedZone("UTC+5:30", 5*3600+1800))
+	fileBackend.SetTimestampLayout("15:04")
+	fileBackend.SetRotateFile(true, 0)
+	fileBackend.Log(Info, []byte("before rotate\n"))
+
+	// 02:13 in UTC is 07:43 in the zone, which starts the hour 07:00.
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.doRotateByHour()
+
+	rotatedFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix+".2019071007")
+	content, err := ioutil.ReadFile(rotatedFilePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", rotatedFilePath, err)
+	}
+	if expectContent := "06:43 before rotate\n"; string(content) != expectContent {
+		t.Errorf("content not match, expect: %s, write: %s", expectContent, content)
+	}
+}
+
+func TestRotateNow(t *testing.T) {
+	fileBackend := createFileBackend(t)
+
+	var wg sync.WaitGroup
+	total := 4000
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < total/4; j++ {
+				fileBackend.Log(Info, []byte("This is one string.\n"))
+			}
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		if err := fileBackend.RotateNow(); err != nil {
+			t.Errorf("rotate now failed, err: %v", err)
+		}
+	}
+	wg.Wait()
+	fileBackend.Close()
+
+	files, err := ioutil.ReadDir(fileBackend.dir)
+	if err != nil {
+		t.Fatalf("read temporary directory failed, err: %v", err)
+	}
+	lines := 0
+	rotatedCount := 0
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), levelNames[Info]+logFileSuffix) {
+			continue
+		}
+		if file.Name() != levelNames[Info]+logFileSuffix {
+			rotatedCount++
+		}
+		content, err := ioutil.ReadFile(path.Join(fileBackend.dir, file.Name()))
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", file.Name(), err)
+		}
+		lines += strings.Count(string(content), "\n")
+	}
+	if rotatedCount != 3 {
+		t.Errorf("count of rotated files should be 3, actual: %v", rotatedCount)
+	}
+	if lines != total {
+		t.Errorf("count of lines should be %v, actual: %v", total, lines)
+	}
+	if err := fileBackend.RotateNow(); err == nil {
+		t.Errorf("rotate closed backend should return error")
+	}
+}
+
+func readGzipFile(t *testing.T, filePath string) string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatalf("open %s failed, err: %v", filePath, err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("read gzip header of %s failed, err: %v", filePath, err)
+	}
+	content, err := ioutil.ReadAll(reader)
+	// the live file is not terminated yet.
+	if err != nil && err != io.ErrUnexpectedEOF {
+		t.Fatalf("decompress %s failed, err: %v", filePath, err)
+	}
+	return string(content)
+}
+
+func TestStreamCompression(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	if err := fileBackend.SetStreamCompression(true); err != nil {
+		t.Fatalf("set stream compression failed, err: %v", err)
+	}
+
+	logFilePath := path.Join(fileBackend.dir, levelNames[Debug]+logFileSuffix+gzipFileSuffix)
+	fileBackend.Log(Debug, []byte("before rotate\n"))
+	fileBackend.Flush()
+	if content := readGzipFile(t, logFilePath); content != "before rotate\n" {
+		t.Errorf("live content not match, expect: before rotate, actual: %s", content)
+	}
+
+	if err := fileBackend.RotateNow(); err != nil {
+		t.Fatalf("rotate now failed, err: %v", err)
+	}
+	fileBackend.Log(Debug, []byte("after rotate\n"))
+	fileBackend.Flush()
+
+	rotatedFiles, err := fileBackend.ListRotatedFiles(Debug)
+	if err != nil || len(rotatedFiles) != 1 || !strings.HasSuffix(rotatedFiles[0], gzipFileSuffix) {
+		t.Fatalf("rotated files not match, actual: %v, err: %v", rotatedFiles, err)
+	}
+	if content := readGzipFile(t, rotatedFiles[0]); content != "before rotate\n" {
+		t.Errorf("rotated content not match, expect: before rotate, actual: %s", content)
+	}
+	if content := readGzipFile(t, logFilePath); content != "after rotate\n" {
+		t.Errorf("live content not match, expect: after rotate, actual: %s", content)
+	}
+}
+
+func TestSetRotateFileInvalid(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	if err := fileBackend.SetRotateFileE(true, -1); err == nil {
+		t.Errorf("negative keep hours should return error")
+	}
+	if fileBackend.rotateByHour {
+		t.Errorf("rotate should not be enabled by invalid arguments")
+	}
+	if err := fileBackend.SetRotateFileE(false, -1); err != nil {
+		t.Errorf("keep hours should be ignored without rotate, err: %v", err)
+	}
+	if err := fileBackend.SetRotateFileE(true, 0); err != nil {
+		t.Errorf("zero keep hours should be valid, err: %v", err)
+	}
+}
+
+func TestSetRotateCheckInterval(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	var nowTime int64 = time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC).UnixNano()
+	fileBackend.getNowTime = func() time.Time {
+		return time.Unix(0, atomic.LoadInt64(&nowTime)).UTC()
+	}
+	fileBackend.SetRotateCheckInterval(time.Millisecond * 10)
+	fileBackend.SetRotateFile(true, 0)
+	fileBackend.Log(Info, []byte("before rotate\n"))
+	atomic.AddInt64(&nowTime, int64(time.Hour))
+
+	rotatedFilePath := path.Join(fileBackend.dir, levelNames[Info]+logFileSuffix+".2019071002")
+	deadline := time.Now().Add(time.Millisecond * 500)
+	for {
+		if _, err := os.Stat(rotatedFilePath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s should be rotated within the check interval", rotatedFilePath)
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+func TestRotateTwiceInSameHour(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	fileBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+	fileBackend.SetRotateFile(true, 0)
+
+	nowTime = nowTime.Add(time.Hour)
+	fileBackend.Log(Info, []byte("first"))
+	fileBackend.doRotateByHour()
+
+	// as if restarted within the same hour.
+	fileBackend.lastRotateTime = 0
+	fileBackend.Log(Info, []byte("second"))
+	fileBackend.doRotateByHour()
+
+	rotatedFilePath := path.Join(fileBackend.dir,
+		levelNames[Info]+logFileSuffix+"."+nowTime.Format(datetimeSuffixLayout))
+	expect := map[string]string{
+		rotatedFilePath:        "first",
+		rotatedFilePath + "-1": "second",
+	}
+	for filePath, expectContent := range expect {
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("read %s failed, err: %v", filePath, err)
+		}
+		if string(content) != expectContent {
+			t.Errorf("%s not match, expect: %s, write: %s", filePath, expectContent, content)
+		}
+		if !rotatedFilenamePattern.MatchString(path.Base(filePath)) {
+			t.Errorf("%s should match rotated pattern", filePath)
+		}
+	}
+}
+
+func benchmarkLines() [][]byte {
+	lines := make([][]byte, 100)
+	for i := range lines {
+		lines[i] = []byte(fmt.Sprintf("This is line %d.\n", i))
+	}
+	return lines
+}
+
+func BenchmarkLogLoop(b *testing.B) {
+	fileBackend := createFileBackend(b)
+	defer fileBackend.Close()
+	lines := benchmarkLines()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			fileBackend.Log(Info, line)
+		}
+	}
+}
+
+func BenchmarkLogBatch(b *testing.B) {
+	fileBackend := createFileBackend(b)
+	defer fileBackend.Close()
+	lines := benchmarkLines()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fileBackend.LogBatch(Info, lines)
+	}
+}
+
+// BenchmarkLogDisabled compares logging a level below the min level through
+// the atomic check and with the mutex held, from parallel goroutines.
+func BenchmarkLogDisabled(b *testing.B) {
+	fileBackend := createFileBackend(b)
+	defer fileBackend.Close()
+	fileBackend.SetMinLevel(Error)
+	line := []byte("This is one string.\n")
+	b.Run("atomic", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				fileBackend.Log(Info, line)
+			}
+		})
+	})
+	b.Run("mutex", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				fileBackend.mutex.Lock()
+				fileBackend.log(1, Info, line)
+				fileBackend.mutex.Unlock()
+			}
+		})
+	})
+}
+
+func TestPauseResume(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.SetPauseBuffer(2, false)
+	filePath := fileBackend.CurrentFilePath(Info)
+
+	fileBackend.Log(Info, []byte("before pause\n"))
+	fileBackend.Pause()
+	fileBackend.Log(Info, []byte("first\n"))
+	fileBackend.Log(Info, []byte("second\n"))
+	fileBackend.Log(Info, []byte("over capacity\n"))
+	fileBackend.Flush()
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if string(content) != "before pause\n" {
+		t.Errorf("content should not be written while paused, actual: %q", content)
+	}
+	if err := fileBackend.Resume(); err != nil {
+		t.Fatalf("resume failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("after resume\n"))
+	fileBackend.Flush()
+	content, err = ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	expect := "before pause\nfirst\nsecond\nafter resume\n"
+	if string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+	if dropped := fileBackend.Stats()[Info].Dropped; dropped != 1 {
+		t.Errorf("dropped not match, expect: 1, actual: %d", dropped)
+	}
+
+	fileBackend.SetPauseBuffer(2, true)
+	fileBackend.Pause()
+	fileBackend.Log(Info, []byte("dropped\n"))
+	fileBackend.Resume()
+	fileBackend.Flush()
+	content, err = ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+}
+
+func TestExitOnFatalFallback(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "fileBackend_test")
+	if err != nil {
+		t.Fatalf("create temporary directoey failed, err: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	// a directory can not be opened as the file of a level.
+	if err := os.MkdirAll(path.Join(tempDir, levelNames[Fatal]+logFileSuffix), defaultDirMode); err != nil {
+		t.Fatalf("create directory failed, err: %v", err)
+	}
+	fileBackend, err := NewFileBackend(tempDir)
+	if err != nil {
+		t.Fatalf("create file backend failed, err: %v", err)
+	}
+	defer fileBackend.Close()
+	var codes []int
+	fileBackend.exit = func(code int) {
+		codes = append(codes, code)
+	}
+	var fallback bytes.Buffer
+	fileBackend.SetFallbackWriter(&fallback)
+	fileBackend.SetExitOnFatal(true)
+
+	fileBackend.Log(Info, []byte("info\n"))
+	fileBackend.Log(Fatal, []byte("fatal\n"))
+	if len(codes) != 1 || codes[0] != 1 {
+		t.Errorf("exit should be called once with 1, actual: %v", codes)
+	}
+	if expect := "FATAL fatal\n"; fallback.String() != expect {
+		t.Errorf("fallback content not match, expect: %q, actual: %q", expect, fallback.String())
+	}
+	// the other files are flushed before exit.
+	filePath := path.Join(tempDir, levelNames[Info]+logFileSuffix)
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if expect := "info\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+}
+
+func TestPauseFatal(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	var codes []int
+	fileBackend.exit = func(code int) {
+		codes = append(codes, code)
+	}
+	fileBackend.SetExitOnFatal(true)
+
+	fileBackend.Pause()
+	fileBackend.Log(Info, []byte("held\n"))
+	fileBackend.Log(Fatal, []byte("fatal\n"))
+	if len(codes) != 1 {
+		t.Errorf("exit should be called while paused, actual: %v", codes)
+	}
+	filePath := fileBackend.CurrentFilePath(Fatal)
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if expect := "fatal\n"; string(content) != expect {
+		t.Errorf("content not match, expect: %q, actual: %q", expect, content)
+	}
+	filePath = fileBackend.CurrentFilePath(Info)
+	content, err = ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read %s failed, err: %v", filePath, err)
+	}
+	if len(content) != 0 {
+		t.Errorf("INFO should be held, actual: %q", content)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package golog
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(file *os.File) error {
+	return errors.New("exclusive lock is not supported on this platform")
+}
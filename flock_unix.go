@@ -0,0 +1,14 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package golog
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive advisory lock on file without blocking, the
+// lock is released when the file is closed.
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
@@ -0,0 +1,38 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package golog
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExclusiveLock(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	if err := fileBackend.SetExclusiveLock(true); err != nil {
+		t.Fatalf("set exclusive lock failed, err: %v", err)
+	}
+
+	other, err := NewFileBackend(fileBackend.dir)
+	if err != nil {
+		t.Fatalf("create file backend failed, err: %v", err)
+	}
+	defer other.Close()
+	if err := other.SetExclusiveLock(true); err == nil {
+		t.Errorf("lock files held by another backend should fail")
+	}
+
+	// reopening keeps the lock of the files.
+	logFilePath := fileBackend.CurrentFilePath(Info)
+	if err := os.Remove(logFilePath); err != nil {
+		t.Fatalf("remove %s failed, err: %v", logFilePath, err)
+	}
+	if err := fileBackend.ReopenFiles(); err != nil {
+		t.Fatalf("reopen files failed, err: %v", err)
+	}
+	if err := fileBackend.ReopenFiles(); err != nil {
+		t.Fatalf("reopen files failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("This is one string.\n"))
+}
@@ -0,0 +1,172 @@
+package golog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var followPollInterval = 100 * time.Millisecond
+
+// follower is handed the new files created under its path, e.g. by rotation,
+// guarded by the mutex of the backend.
+type follower struct {
+	filepath string
+	next     []*os.File
+	// latest is the last file followed or handed.
+	latest os.FileInfo
+}
+
+// Follow tails the current file of level from its end, sending each line
+// flushed into the file. When the file is rotated, the rest of the old file
+// is read and the new current file is followed from its beginning. The
+// channel is closed once ctx is done.
+func (s *FileBackend) Follow(ctx context.Context, level Level) (<-chan []byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	writer := s.writer[level]
+	if writer == nil {
+		return nil, fmt.Errorf("level %v has no open file", level)
+	}
+	filepath := writer.filePath
+	if strings.HasSuffix(filepath, gzipFileSuffix) {
+		return nil, fmt.Errorf("follow compressed file %s is not supported", filepath)
+	}
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	f := &follower{filepath: filepath, latest: info}
+	s.followers = append(s.followers, f)
+	lines := make(chan []byte)
+	go s.follow(ctx, f, file, lines)
+	return lines, nil
+}
+
+// notifyFollowers opens filepath for the followers of it, called with the
+// mutex held once the file is opened for writing. Reopening the same file,
+// e.g. by ReopenFiles, keeps the followers reading it.
+func (s *FileBackend) notifyFollowers(filepath string) {
+	for _, f := range s.followers {
+		if f.filepath != filepath {
+			continue
+		}
+		file, err := os.Open(filepath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "open %s failed: %v", filepath, err)
+			continue
+		}
+		info, err := file.Stat()
+		if err != nil || os.SameFile(info, f.latest) {
+			file.Close()
+			continue
+		}
+		f.next = append(f.next, file)
+		f.latest = info
+	}
+}
+
+func (s *FileBackend) unfollow(f *follower) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, other := range s.followers {
+		if other == f {
+			s.followers = append(s.followers[:i], s.followers[i+1:]...)
+			break
+		}
+	}
+	for _, file := range f.next {
+		file.Close()
+	}
+	f.next = nil
+}
+
+// nextFile returns the file opened after the followed one, the followed file
+// is no longer written then.
+func (s *FileBackend) nextFile(f *follower) *os.File {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(f.next) == 0 {
+		return nil
+	}
+	file := f.next[0]
+	f.next = f.next[1:]
+	return file
+}
+
+func (s *FileBackend) follow(ctx context.Context, f *follower, file *os.File, lines chan<- []byte) {
+	defer close(lines)
+	defer s.unfollow(f)
+	// file is replaced once rotated.
+	defer func() {
+		file.Close()
+	}()
+	reader := bufio.NewReader(file)
+	var partial []byte
+	// readLines sends the lines read until EOF, it returns false once ctx is
+	// done.
+	readLines := func() bool {
+		for {
+			line, err := reader.ReadBytes('\n')
+			partial = append(partial, line...)
+			if err != nil {
+				if err != io.EOF {
+					fmt.Fprintf(os.Stderr, "read %s failed: %v", f.filepath, err)
+				}
+				return true
+			}
+			select {
+			case lines <- partial:
+			case <-ctx.Done():
+				return false
+			}
+			partial = nil
+		}
+	}
+	ticker := time.NewTicker(followPollInterval)
+	defer ticker.Stop()
+	for {
+		if !readLines() {
+			return
+		}
+		if newFile := s.nextFile(f); newFile != nil {
+			// the rest written before the file was replaced.
+			if !readLines() {
+				newFile.Close()
+				return
+			}
+			if len(partial) > 0 {
+				select {
+				case lines <- partial:
+				case <-ctx.Done():
+					newFile.Close()
+					return
+				}
+				partial = nil
+			}
+			file.Close()
+			file = newFile
+			reader.Reset(file)
+			// the new file is read without waiting for the tick.
+			continue
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package golog
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func receiveLine(t *testing.T, lines <-chan []byte) string {
+	select {
+	case line, ok := <-lines:
+		if !ok {
+			t.Fatalf("lines closed unexpectedly")
+		}
+		return string(line)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("receive line timeout")
+	}
+	return ""
+}
+
+func TestFollow(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.Log(Info, []byte("before follow\n"))
+	fileBackend.Flush()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines, err := fileBackend.Follow(ctx, Info)
+	if err != nil {
+		t.Fatalf("follow failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("first\n"))
+	fileBackend.Log(Info, []byte("second\n"))
+	fileBackend.Flush()
+	for _, expect := range []string{"first\n", "second\n"} {
+		if actual := receiveLine(t, lines); actual != expect {
+			t.Errorf("line not match, expect: %q, actual: %q", expect, actual)
+		}
+	}
+
+	fileBackend.Log(Info, []byte("before rotate\n"))
+	if err := fileBackend.RotateNow(); err != nil {
+		t.Fatalf("rotate failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("after rotate\n"))
+	fileBackend.Flush()
+	for _, expect := range []string{"before rotate\n", "after rotate\n"} {
+		if actual := receiveLine(t, lines); actual != expect {
+			t.Errorf("line not match, expect: %q, actual: %q", expect, actual)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-lines:
+		if ok {
+			t.Errorf("lines should be closed after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("lines not closed after cancel")
+	}
+
+	if _, err := fileBackend.Follow(context.Background(), Level(100)); err == nil {
+		t.Errorf("follow invalid level should fail")
+	}
+}
+
+func TestFollowRotations(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, err := fileBackend.Follow(ctx, Info)
+	if err != nil {
+		t.Fatalf("follow failed, err: %v", err)
+	}
+
+	const count = 3000
+	received := make(chan []string)
+	go func() {
+		var actual []string
+		for len(actual) < count {
+			select {
+			case line := <-lines:
+				actual = append(actual, string(line))
+			case <-time.After(5 * time.Second):
+				received <- actual
+				return
+			}
+		}
+		received <- actual
+	}()
+	// several rotations happen between the polls.
+	for i := 0; i < count; i++ {
+		fileBackend.Log(Info, []byte(strconv.Itoa(i)+"\n"))
+		if i%10 == 9 {
+			fileBackend.Flush()
+			if err := fileBackend.RotateNow(); err != nil {
+				t.Fatalf("rotate failed, err: %v", err)
+			}
+		}
+	}
+	actual := <-received
+	if len(actual) != count {
+		t.Fatalf("line count not match, expect: %d, actual: %d", count, len(actual))
+	}
+	for i, line := range actual {
+		if expect := strconv.Itoa(i) + "\n"; line != expect {
+			t.Fatalf("line not match, expect: %q, actual: %q", expect, line)
+		}
+	}
+}
+
+func TestFollowReopenSameFile(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	fileBackend.Log(Info, []byte("before follow\n"))
+	fileBackend.Flush()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, err := fileBackend.Follow(ctx, Info)
+	if err != nil {
+		t.Fatalf("follow failed, err: %v", err)
+	}
+
+	fileBackend.Log(Info, []byte("a\n"))
+	fileBackend.Flush()
+	if err := fileBackend.ReopenFiles(); err != nil {
+		t.Fatalf("reopen files failed, err: %v", err)
+	}
+	fileBackend.Log(Info, []byte("b\n"))
+	fileBackend.Flush()
+	for _, expect := range []string{"a\n", "b\n"} {
+		if actual := receiveLine(t, lines); actual != expect {
+			t.Errorf("line not match, expect: %q, actual: %q", expect, actual)
+		}
+	}
+	// the reopened file is not read again.
+	select {
+	case line := <-lines:
+		t.Errorf("unexpected line: %q", line)
+	case <-time.After(followPollInterval * 3):
+	}
+}
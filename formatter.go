@@ -0,0 +1,70 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Formatter renders the content passed to Log into the bytes written out.
+type Formatter interface {
+	Format(level Level, t time.Time, msg []byte) []byte
+}
+
+// Encoder renders each entry into a record of any format, e.g. a length
+// prefixed binary one. Unlike a Formatter, msg is passed as is without the
+// text options applied.
+type Encoder interface {
+	Encode(level Level, t time.Time, msg []byte) []byte
+}
+
+type JSONFormatter struct {
+	TimestampLayout string
+}
+
+type jsonEntry struct {
+	Timestamp string `json:"ts"`
+	Level     string `json:"level"`
+	Message   string `json:"msg"`
+}
+
+func (f *JSONFormatter) Format(level Level, t time.Time, msg []byte) []byte {
+	layout := f.TimestampLayout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	entry := jsonEntry{
+		Timestamp: t.Format(layout),
+		Level:     levelNames[level],
+		Message:   string(bytes.TrimRight(msg, "\n")),
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(&entry); err != nil {
+		return msg
+	}
+	return buffer.Bytes()
+}
+
+// LogfmtFormatter renders lines like ts=... level=INFO msg="...", values
+// with spaces, equals signs or quotes are quoted.
+type LogfmtFormatter struct {
+	TimestampLayout string
+}
+
+func (f *LogfmtFormatter) Format(level Level, t time.Time, msg []byte) []byte {
+	layout := f.TimestampLayout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("ts=")
+	buffer.WriteString(quoteIfNeeded(t.Format(layout)))
+	buffer.WriteString(" level=")
+	buffer.WriteString(quoteIfNeeded(level.String()))
+	buffer.WriteString(" msg=")
+	buffer.WriteString(quoteIfNeeded(string(bytes.TrimRight(msg, "\n"))))
+	buffer.WriteByte('\n')
+	return buffer.Bytes()
+}
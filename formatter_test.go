@@ -0,0 +1,68 @@
+package golog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	formatter := &JSONFormatter{}
+	timePoint := time.Date(2019, 6, 10, 12, 0, 0, 0, time.UTC)
+	messages := []string{
+		"This is a plain string.",
+		"This is a string with \"quotes\".",
+		"This is a string\nwith newlines.\n",
+		"<html> & friends",
+	}
+	for level := levelMin; level <= levelMax; level++ {
+		for _, message := range messages {
+			output := formatter.Format(level, timePoint, []byte(message))
+			if !strings.HasSuffix(string(output), "}\n") ||
+				strings.Count(string(output), "\n") != 1 {
+				t.Errorf("output should be a single line, actual: %q", output)
+			}
+			var entry map[string]string
+			if err := json.Unmarshal(output, &entry); err != nil {
+				t.Fatalf("invalid json: %s, err: %v", output, err)
+			}
+			if entry["ts"] != "2019-06-10T12:00:00Z" {
+				t.Errorf("ts not match, actual: %v", entry["ts"])
+			}
+			if entry["level"] != levelNames[level] {
+				t.Errorf("level not match, expect: %v, actual: %v", levelNames[level], entry["level"])
+			}
+			if entry["msg"] != strings.TrimRight(message, "\n") {
+				t.Errorf("msg not match, expect: %q, actual: %q", message, entry["msg"])
+			}
+		}
+	}
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	formatter := &LogfmtFormatter{}
+	timePoint := time.Date(2019, 6, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"plain":           `msg=plain`,
+		"with spaces\n":   `msg="with spaces"`,
+		"key=value":       `msg="key=value"`,
+		`say "hi"`:        `msg="say \"hi\""`,
+		"multiple\nlines": `msg="multiple\nlines"`,
+		"":                `msg=""`,
+		`back\slash`:      `msg=back\slash`,
+	}
+	for message, expectMsg := range cases {
+		output := formatter.Format(Warning, timePoint, []byte(message))
+		expect := "ts=2019-06-10T12:00:00Z level=WARNING " + expectMsg + "\n"
+		if string(output) != expect {
+			t.Errorf("output not match, expect: %q, actual: %q", expect, output)
+		}
+	}
+
+	formatter.TimestampLayout = "2006-01-02 15:04:05"
+	output := formatter.Format(Info, timePoint, []byte("plain"))
+	if expect := "ts=\"2019-06-10 12:00:00\" level=INFO msg=plain\n"; string(output) != expect {
+		t.Errorf("output not match, expect: %q, actual: %q", expect, output)
+	}
+}
@@ -1,5 +1,11 @@
 package golog
 
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
 type Level int
 
 const (
@@ -8,12 +14,14 @@ const (
 	Warning
 	Error
 	Fatal
-	levelCount int = iota
 )
 
 const (
 	levelMin Level = Debug
 	levelMax Level = Fatal
+
+	// levelLowest is below every level, custom ones included.
+	levelLowest Level = math.MinInt32
 )
 
 var (
@@ -24,4 +32,134 @@ var (
 		Error:   "ERROR",
 		Fatal:   "FATAL",
 	}
+	// levelOrder holds the registered levels from the least severe one.
+	levelOrder = []Level{Debug, Info, Warning, Error, Fatal}
+	// levelRanks maps the registered levels to twice their index in
+	// levelOrder, the odd ranks are left for the unregistered values.
+	levelRanks = map[Level]int{Debug: 0, Info: 2, Warning: 4, Error: 6, Fatal: 8}
 )
+
+// RegisterLevel adds a custom level besides the builtin ones, ordered by
+// value among them. So a custom level is placed below Debug with a negative
+// value or above Fatal with a value larger than four, use RegisterLevelAbove
+// to place it between builtin levels. A level above Fatal flushes all files
+// on each log unless SetFlushOnLevel is raised above it.
+//
+// RegisterLevel is not safe to call concurrently with logging, call it
+// during program initialization.
+func RegisterLevel(value int, name string) error {
+	if err := checkNewLevel(value, name); err != nil {
+		return err
+	}
+	index := len(levelOrder)
+	for i, registered := range levelOrder {
+		if registered > Level(value) {
+			index = i
+			break
+		}
+	}
+	addLevel(index, Level(value), name)
+	return nil
+}
+
+// RegisterLevelAbove is RegisterLevel placing the level right above lower,
+// regardless of value, e.g. a NOTICE level above Info and below Warning.
+func RegisterLevelAbove(value int, name string, lower Level) error {
+	if err := checkNewLevel(value, name); err != nil {
+		return err
+	}
+	index, ok := levelRanks[lower]
+	if !ok {
+		return fmt.Errorf("invalid level: %v", lower)
+	}
+	addLevel(index/2+1, Level(value), name)
+	return nil
+}
+
+func addLevel(index int, level Level, name string) {
+	levelNames[level] = name
+	levelOrder = append(levelOrder[:index], append([]Level{level}, levelOrder[index:]...)...)
+	updateLevelRanks()
+	updateRotatedFilenamePatterns()
+}
+
+func updateLevelRanks() {
+	levelRanks = make(map[Level]int, len(levelOrder))
+	for i, level := range levelOrder {
+		levelRanks[level] = i * 2
+	}
+}
+
+func checkNewLevel(value int, name string) error {
+	level := Level(value)
+	if level == levelLowest {
+		return fmt.Errorf("level value %d is reserved", value)
+	}
+	if name == "" || strings.ContainsAny(name, ". /\\") {
+		return fmt.Errorf("invalid level name: %q", name)
+	}
+	if registered, ok := levelNames[level]; ok {
+		return fmt.Errorf("level %d already registered as %s", value, registered)
+	}
+	for _, registered := range levelNames {
+		if strings.EqualFold(registered, name) {
+			return fmt.Errorf("level name %s already registered", name)
+		}
+	}
+	return nil
+}
+
+func isValidLevel(level Level) bool {
+	_, ok := levelNames[level]
+	return ok
+}
+
+// levels returns all registered levels from the least severe one.
+func levels() []Level {
+	return append([]Level(nil), levelOrder...)
+}
+
+// rank orders the levels by severity. An unregistered value is placed right
+// above the most severe registered level with a smaller value.
+func (l Level) rank() int {
+	if rank, ok := levelRanks[l]; ok {
+		return rank
+	}
+	rank := -1
+	for i, registered := range levelOrder {
+		if registered < l {
+			rank = i*2 + 1
+		}
+	}
+	return rank
+}
+
+// below reports whether l is less severe than other, which differs from
+// comparing the values once a level is registered by RegisterLevelAbove.
+func (l Level) below(other Level) bool {
+	// builtin levels never change their order.
+	if l >= levelMin && l <= levelMax && other >= levelMin && other <= levelMax {
+		return l < other
+	}
+	lRank, otherRank := l.rank(), other.rank()
+	if lRank != otherRank {
+		return lRank < otherRank
+	}
+	return l < other
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+func ParseLevel(name string) (Level, error) {
+	for level, registered := range levelNames {
+		if strings.EqualFold(registered, name) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown level: %q", name)
+}
@@ -7,11 +7,141 @@ func TestLevel(t *testing.T) {
 		t.Errorf("levelMin should be zero.")
 	}
 
-	if levelCount != 5 {
-		t.Errorf("levelCount should be five.")
+	if len(levels()) != 5 {
+		t.Errorf("count of levels should be five.")
 	}
 
 	if levelMax != Level(4) {
 		t.Errorf("levelMax should be four.")
 	}
 }
+
+func registerTestLevel(t *testing.T, value int, name string) Level {
+	if err := RegisterLevel(value, name); err != nil {
+		t.Fatalf("register level %s failed, err: %v", name, err)
+	}
+	t.Cleanup(func() {
+		unregisterTestLevel(Level(value))
+	})
+	return Level(value)
+}
+
+func unregisterTestLevel(level Level) {
+	delete(levelNames, level)
+	for i, registered := range levelOrder {
+		if registered == level {
+			levelOrder = append(levelOrder[:i:i], levelOrder[i+1:]...)
+			break
+		}
+	}
+	updateLevelRanks()
+	updateRotatedFilenamePatterns()
+}
+
+func TestRegisterLevel(t *testing.T) {
+	trace := registerTestLevel(t, -1, "TRACE")
+	audit := registerTestLevel(t, 10, "AUDIT")
+
+	if !isValidLevel(trace) || !isValidLevel(audit) {
+		t.Errorf("registered levels should be valid")
+	}
+	expect := []Level{trace, Debug, Info, Warning, Error, Fatal, audit}
+	actual := levels()
+	if len(actual) != len(expect) {
+		t.Fatalf("levels not match, expect: %v, actual: %v", expect, actual)
+	}
+	for i := range expect {
+		if actual[i] != expect[i] {
+			t.Errorf("levels not match, expect: %v, actual: %v", expect, actual)
+		}
+	}
+	if matched := rotatedFilenamePattern.FindString("TRACE.log.2019061012"); matched == "" {
+		t.Errorf("rotated pattern should match custom level")
+	}
+
+	if err := RegisterLevel(int(Info), "DUPLICATED"); err == nil {
+		t.Errorf("register duplicated value should fail")
+	}
+	if err := RegisterLevel(11, "trace"); err == nil {
+		t.Errorf("register duplicated name should fail")
+	}
+	if err := RegisterLevel(12, "BAD.NAME"); err == nil {
+		t.Errorf("register invalid name should fail")
+	}
+}
+
+func TestRegisterLevelAbove(t *testing.T) {
+	if err := RegisterLevelAbove(20, "NOTICE", Info); err != nil {
+		t.Fatalf("register level NOTICE failed, err: %v", err)
+	}
+	notice := Level(20)
+	t.Cleanup(func() {
+		unregisterTestLevel(notice)
+	})
+	if err := RegisterLevelAbove(21, "UNKNOWN", Level(100)); err == nil {
+		t.Errorf("register above unregistered level should fail")
+	}
+
+	expect := []Level{Debug, Info, notice, Warning, Error, Fatal}
+	actual := levels()
+	if len(actual) != len(expect) {
+		t.Fatalf("levels not match, expect: %v, actual: %v", expect, actual)
+	}
+	for i := range expect {
+		if actual[i] != expect[i] {
+			t.Errorf("levels not match, expect: %v, actual: %v", expect, actual)
+		}
+	}
+	if !Info.below(notice) || !notice.below(Warning) || notice.below(Info) {
+		t.Errorf("NOTICE should be between INFO and WARNING")
+	}
+	// unregistered values stay ordered by value among the builtin levels.
+	if !Fatal.below(Fatal+1) || !levelLowest.below(Debug) {
+		t.Errorf("unregistered levels not ordered by value")
+	}
+
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	if err := fileBackend.SetMinLevel(notice); err != nil {
+		t.Fatalf("set min level failed, err: %v", err)
+	}
+	if fileBackend.IsLevelEnabled(Info) || !fileBackend.IsLevelEnabled(notice) ||
+		!fileBackend.IsLevelEnabled(Warning) {
+		t.Errorf("levels below NOTICE should be disabled only")
+	}
+}
+
+func TestLevelString(t *testing.T) {
+	if Warning.String() != "WARNING" {
+		t.Errorf("expect: WARNING, actual: %v", Warning.String())
+	}
+	if Level(100).String() != "Level(100)" {
+		t.Errorf("expect: Level(100), actual: %v", Level(100).String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]Level{
+		"DEBUG":   Debug,
+		"info":    Info,
+		"Warning": Warning,
+		"eRRoR":   Error,
+		"FATAL":   Fatal,
+	}
+	for name, expect := range cases {
+		level, err := ParseLevel(name)
+		if err != nil {
+			t.Errorf("parse %s failed, err: %v", name, err)
+			continue
+		}
+		if level != expect {
+			t.Errorf("parse %s, expect: %v, actual: %v", name, expect, level)
+		}
+	}
+
+	for _, name := range []string{"", "WARN", "unknown"} {
+		if _, err := ParseLevel(name); err == nil {
+			t.Errorf("parse %q should fail", name)
+		}
+	}
+}
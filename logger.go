@@ -0,0 +1,182 @@
+package golog
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Logger struct {
+	mutex    sync.RWMutex
+	backends []Backend
+	minLevel Level
+	fields   map[string]interface{}
+	// renderedFields is fields rendered as " key=value" pairs, appended to
+	// each line.
+	renderedFields string
+}
+
+func NewLogger(backends ...Backend) *Logger {
+	return &Logger{
+		backends: append([]Backend(nil), backends...),
+		minLevel: levelLowest,
+	}
+}
+
+// With returns a child logger writing into the same backends, which appends
+// fields as key=value pairs to each line. Fields of the child override the
+// same keys of its parent.
+func (s *Logger) With(fields map[string]interface{}) *Logger {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	merged := make(map[string]interface{}, len(s.fields)+len(fields))
+	for key, value := range s.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &Logger{
+		backends:       append([]Backend(nil), s.backends...),
+		minLevel:       s.minLevel,
+		fields:         merged,
+		renderedFields: renderFields(merged),
+	}
+}
+
+func renderFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var builder strings.Builder
+	for _, key := range keys {
+		builder.WriteByte(' ')
+		builder.WriteString(key)
+		builder.WriteByte('=')
+		builder.WriteString(quoteIfNeeded(fmt.Sprint(fields[key])))
+	}
+	return builder.String()
+}
+
+// appendFields inserts the fields before the trailing newline of content.
+func (s *Logger) appendFields(content []byte) []byte {
+	if s.renderedFields == "" {
+		return content
+	}
+	body := bytes.TrimRight(content, "\n")
+	result := make([]byte, 0, len(content)+len(s.renderedFields))
+	result = append(result, body...)
+	result = append(result, s.renderedFields...)
+	return append(result, content[len(body):]...)
+}
+
+func (s *Logger) SetMinLevel(level Level) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.minLevel = level
+}
+
+// IsLevelEnabled reports whether a message of level would be written by any
+// backend, so expensive log statements can be skipped.
+func (s *Logger) IsLevelEnabled(level Level) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if level.below(s.minLevel) {
+		return false
+	}
+	for _, backend := range s.backends {
+		enabler, ok := backend.(LevelEnabler)
+		if !ok || enabler.IsLevelEnabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Logger) AddBackend(b Backend) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.backends = append(s.backends, b)
+}
+
+func (s *Logger) RemoveBackend(b Backend) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	backends := make([]Backend, 0, len(s.backends))
+	for _, backend := range s.backends {
+		if backend != b {
+			backends = append(backends, backend)
+		}
+	}
+	s.backends = backends
+}
+
+func (s *Logger) Log(level Level, content []byte) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if level.below(s.minLevel) {
+		return
+	}
+	content = s.appendFields(content)
+	for _, backend := range s.backends {
+		backend.Log(level, content)
+	}
+}
+
+func (s *Logger) Flush() {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, backend := range s.backends {
+		backend.Flush()
+	}
+}
+
+func (s *Logger) Close() {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, backend := range s.backends {
+		backend.Close()
+	}
+}
+
+// CloseWithTimeout is like Close but returns an error once d elapses, the
+// backends still closing are abandoned.
+func (s *Logger) CloseWithTimeout(d time.Duration) error {
+	return closeWithTimeout(s.Close, d)
+}
+
+func (s *Logger) logf(level Level, format string, args ...interface{}) {
+	if !s.IsLevelEnabled(level) {
+		return
+	}
+	content := fmt.Sprintf(format, args...)
+	if len(content) == 0 || content[len(content)-1] != '\n' {
+		content += "\n"
+	}
+	s.Log(level, []byte(content))
+}
+
+func (s *Logger) Debugf(format string, args ...interface{}) {
+	s.logf(Debug, format, args...)
+}
+
+func (s *Logger) Infof(format string, args ...interface{}) {
+	s.logf(Info, format, args...)
+}
+
+func (s *Logger) Warningf(format string, args ...interface{}) {
+	s.logf(Warning, format, args...)
+}
+
+func (s *Logger) Errorf(format string, args ...interface{}) {
+	s.logf(Error, format, args...)
+}
+
+func (s *Logger) Fatalf(format string, args ...interface{}) {
+	s.logf(Fatal, format, args...)
+}
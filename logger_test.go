@@ -0,0 +1,215 @@
+package golog
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type testEntry struct {
+	level   Level
+	content string
+}
+
+type testBackend struct {
+	mutex   sync.Mutex
+	entries []testEntry
+}
+
+func (s *testBackend) Log(level Level, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = append(s.entries, testEntry{level, string(content)})
+}
+
+func (s *testBackend) Flush() {
+}
+
+func (s *testBackend) Close() {
+}
+
+func TestLoggerImplementsBackend(t *testing.T) {
+	var _ Backend = (*Logger)(nil)
+}
+
+func TestLoggerFanOut(t *testing.T) {
+	first := &testBackend{}
+	second := &testBackend{}
+	logger := NewLogger(first)
+	logger.AddBackend(second)
+
+	logger.Infof("hello %s, %d", "world", 42)
+	logger.Errorf("failed: %v\n", "timeout")
+
+	expect := []testEntry{
+		{Info, "hello world, 42\n"},
+		{Error, "failed: timeout\n"},
+	}
+	for _, backend := range []*testBackend{first, second} {
+		if len(backend.entries) != len(expect) {
+			t.Fatalf("count of entries should be %v, actual: %v",
+				len(expect), len(backend.entries))
+		}
+		for i, entry := range backend.entries {
+			if entry != expect[i] {
+				t.Errorf("entry not match, expect: %v, actual: %v", expect[i], entry)
+			}
+		}
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	backend := &testBackend{}
+	logger := NewLogger(backend)
+
+	logger.Debugf("debug")
+	logger.Infof("info")
+	logger.Warningf("warning")
+	logger.Errorf("error")
+	logger.Fatalf("fatal")
+
+	if len(backend.entries) != len(levels()) {
+		t.Fatalf("count of entries should be %v, actual: %v",
+			len(levels()), len(backend.entries))
+	}
+	for i, entry := range backend.entries {
+		if entry.level != Level(i) {
+			t.Errorf("level not match, expect: %v, actual: %v", Level(i), entry.level)
+		}
+	}
+}
+
+func TestLoggerRemoveBackend(t *testing.T) {
+	first := &testBackend{}
+	second := &testBackend{}
+	logger := NewLogger(first, second)
+	logger.RemoveBackend(first)
+
+	logger.Infof("hello")
+	if len(first.entries) != 0 {
+		t.Errorf("removed backend should receive nothing, actual: %v", first.entries)
+	}
+	if len(second.entries) != 1 {
+		t.Errorf("count of entries should be 1, actual: %v", len(second.entries))
+	}
+}
+
+type countingStringer struct {
+	count *int
+}
+
+func (s countingStringer) String() string {
+	*s.count++
+	return "counted"
+}
+
+func TestLoggerIsLevelEnabled(t *testing.T) {
+	backend := &testBackend{}
+	logger := NewLogger(backend)
+	logger.SetMinLevel(Info)
+
+	if logger.IsLevelEnabled(Debug) {
+		t.Errorf("debug should be disabled")
+	}
+	if !logger.IsLevelEnabled(Error) {
+		t.Errorf("error should be enabled")
+	}
+
+	formatCount := 0
+	logger.Debugf("%v", countingStringer{&formatCount})
+	if formatCount != 0 || len(backend.entries) != 0 {
+		t.Errorf("disabled level should not be formatted, count: %v", formatCount)
+	}
+	logger.Infof("%v", countingStringer{&formatCount})
+	if formatCount != 1 || len(backend.entries) != 1 {
+		t.Errorf("enabled level should be formatted once, count: %v", formatCount)
+	}
+}
+
+func TestLoggerIsLevelEnabledByBackend(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	if err := fileBackend.SetMinLevel(Warning); err != nil {
+		t.Fatalf("set min level failed, err: %v", err)
+	}
+	logger := NewLogger(fileBackend)
+
+	if logger.IsLevelEnabled(Info) {
+		t.Errorf("info should be disabled by backend")
+	}
+	if !logger.IsLevelEnabled(Warning) {
+		t.Errorf("warning should be enabled")
+	}
+	logger.AddBackend(&testBackend{})
+	if !logger.IsLevelEnabled(Info) {
+		t.Errorf("info should be enabled by backend without level filter")
+	}
+}
+
+func BenchmarkLoggerEnabled(b *testing.B) {
+	logger := NewLogger(NopBackend{})
+	for i := 0; i < b.N; i++ {
+		logger.Debugf("benchmark %d %s", i, "message")
+	}
+}
+
+func BenchmarkLoggerDisabled(b *testing.B) {
+	logger := NewLogger(NopBackend{})
+	logger.SetMinLevel(Info)
+	for i := 0; i < b.N; i++ {
+		logger.Debugf("benchmark %d %s", i, "message")
+	}
+}
+
+func TestLoggerWith(t *testing.T) {
+	backend := &testBackend{}
+	logger := NewLogger(backend)
+	child := logger.With(map[string]interface{}{"user": "alice", "id": 42})
+	grandchild := child.With(map[string]interface{}{"id": 43, "path": "/a b"})
+
+	logger.Infof("root")
+	child.Infof("child")
+	grandchild.Errorf("grandchild\n")
+	child.Log(Warning, []byte("raw"))
+
+	expect := []testEntry{
+		{Info, "root\n"},
+		{Info, "child id=42 user=alice\n"},
+		{Error, "grandchild id=43 path=\"/a b\" user=alice\n"},
+		{Warning, "raw id=42 user=alice"},
+	}
+	if len(backend.entries) != len(expect) {
+		t.Fatalf("count of entries should be %v, actual: %v", len(expect), len(backend.entries))
+	}
+	for i, entry := range backend.entries {
+		if entry != expect[i] {
+			t.Errorf("entry not match, expect: %v, actual: %v", expect[i], entry)
+		}
+	}
+}
+
+type blockingCloseBackend struct {
+	NopBackend
+	release chan struct{}
+}
+
+func (s *blockingCloseBackend) Close() {
+	<-s.release
+}
+
+func TestLoggerCloseWithTimeout(t *testing.T) {
+	backend := &blockingCloseBackend{release: make(chan struct{})}
+	defer close(backend.release)
+	logger := NewLogger(backend)
+
+	start := time.Now()
+	if err := logger.CloseWithTimeout(50 * time.Millisecond); err == nil {
+		t.Errorf("close blocked backend should time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("close should return after the timeout, elapsed: %v", elapsed)
+	}
+	if err := NewLogger(NopBackend{}).CloseWithTimeout(time.Second); err != nil {
+		t.Errorf("close should succeed, err: %v", err)
+	}
+}
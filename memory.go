@@ -0,0 +1,96 @@
+package golog
+
+import "sync"
+
+type Entry struct {
+	Level   Level
+	Content []byte
+}
+
+// MemoryBackend keeps every logged entry in memory, mostly used in tests.
+type MemoryBackend struct {
+	mutex   sync.Mutex
+	entries []Entry
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{}
+}
+
+func (s *MemoryBackend) Log(level Level, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = append(s.entries, Entry{
+		Level:   level,
+		Content: append([]byte(nil), content...),
+	})
+}
+
+func (s *MemoryBackend) Flush() {
+}
+
+func (s *MemoryBackend) Close() {
+}
+
+func (s *MemoryBackend) Entries() []Entry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Entry(nil), s.entries...)
+}
+
+func (s *MemoryBackend) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = nil
+}
+
+// RingBufferBackend keeps the last entries of all levels in memory, the
+// oldest ones are evicted once it is full.
+type RingBufferBackend struct {
+	mutex   sync.Mutex
+	entries []Entry
+	// next is the index the next entry is stored at.
+	next int
+	full bool
+}
+
+// NewRingBufferBackend creates a RingBufferBackend keeping n entries, at
+// least one.
+func NewRingBufferBackend(n int) *RingBufferBackend {
+	if n < 1 {
+		n = 1
+	}
+	return &RingBufferBackend{entries: make([]Entry, n)}
+}
+
+func (s *RingBufferBackend) Log(level Level, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries[s.next] = Entry{
+		Level:   level,
+		Content: append([]byte(nil), content...),
+	}
+	s.next++
+	if s.next == len(s.entries) {
+		s.next = 0
+		s.full = true
+	}
+}
+
+func (s *RingBufferBackend) Flush() {
+}
+
+func (s *RingBufferBackend) Close() {
+}
+
+// Recent returns the kept entries from the oldest to the newest.
+func (s *RingBufferBackend) Recent() []Entry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.full {
+		return append([]Entry(nil), s.entries[:s.next]...)
+	}
+	result := make([]Entry, 0, len(s.entries))
+	result = append(result, s.entries[s.next:]...)
+	return append(result, s.entries[:s.next]...)
+}
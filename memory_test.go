@@ -0,0 +1,71 @@
+package golog
+
+import "testing"
+
+func TestMemoryBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*MemoryBackend)(nil)
+}
+
+func TestMemoryBackendEntries(t *testing.T) {
+	memoryBackend := NewMemoryBackend()
+	content := []byte("first")
+	memoryBackend.Log(Info, content)
+	copy(content, "xxxxx")
+	memoryBackend.Log(Error, []byte("second"))
+	memoryBackend.Log(Debug, []byte("third"))
+
+	expect := []Entry{
+		{Info, []byte("first")},
+		{Error, []byte("second")},
+		{Debug, []byte("third")},
+	}
+	entries := memoryBackend.Entries()
+	if len(entries) != len(expect) {
+		t.Fatalf("count of entries should be %v, actual: %v", len(expect), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Level != expect[i].Level || string(entry.Content) != string(expect[i].Content) {
+			t.Errorf("entry not match, expect: %v, actual: %v", expect[i], entry)
+		}
+	}
+
+	memoryBackend.Reset()
+	if entries := memoryBackend.Entries(); len(entries) != 0 {
+		t.Errorf("entries should be empty after reset, actual: %v", entries)
+	}
+	memoryBackend.Log(Warning, []byte("fourth"))
+	if entries := memoryBackend.Entries(); len(entries) != 1 || string(entries[0].Content) != "fourth" {
+		t.Errorf("entries not match after reset, actual: %v", entries)
+	}
+}
+
+func TestRingBufferBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*RingBufferBackend)(nil)
+}
+
+func TestRingBufferBackendRecent(t *testing.T) {
+	ringBufferBackend := NewRingBufferBackend(3)
+	ringBufferBackend.Log(Info, []byte("first"))
+	ringBufferBackend.Log(Error, []byte("second"))
+	if entries := ringBufferBackend.Recent(); len(entries) != 2 || string(entries[0].Content) != "first" {
+		t.Errorf("entries before full not match, actual: %v", entries)
+	}
+	ringBufferBackend.Log(Debug, []byte("third"))
+	ringBufferBackend.Log(Warning, []byte("fourth"))
+	ringBufferBackend.Log(Fatal, []byte("fifth"))
+
+	expect := []Entry{
+		{Debug, []byte("third")},
+		{Warning, []byte("fourth")},
+		{Fatal, []byte("fifth")},
+	}
+	entries := ringBufferBackend.Recent()
+	if len(entries) != len(expect) {
+		t.Fatalf("count of entries should be %v, actual: %v", len(expect), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Level != expect[i].Level || string(entry.Content) != string(expect[i].Content) {
+			t.Errorf("entry not match, expect: %v, actual: %v", expect[i], entry)
+		}
+	}
+}
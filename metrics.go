@@ -0,0 +1,41 @@
+package golog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// MetricsSource exposes the counters of a backend, so they can be exported
+// into a metrics system. FileBackend implements it.
+type MetricsSource interface {
+	Stats() map[Level]BackendStats
+	Rotations() uint64
+}
+
+// WritePrometheusText writes the counters of source in the Prometheus text
+// exposition format, e.g. from a /metrics handler.
+func WritePrometheusText(w io.Writer, source MetricsSource) error {
+	writer := bufio.NewWriter(w)
+	stats := source.Stats()
+	counters := []struct {
+		name  string
+		help  string
+		value func(BackendStats) uint64
+	}{
+		{"golog_lines_total", "Lines written.", func(s BackendStats) uint64 { return s.Lines }},
+		{"golog_bytes_total", "Bytes written.", func(s BackendStats) uint64 { return s.Bytes }},
+		{"golog_dropped_total", "Lines dropped.", func(s BackendStats) uint64 { return s.Dropped }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name)
+		for _, level := range levels() {
+			if levelStats, ok := stats[level]; ok {
+				fmt.Fprintf(writer, "%s{level=%q} %d\n", counter.name, levelNames[level], counter.value(levelStats))
+			}
+		}
+	}
+	fmt.Fprintf(writer, "# HELP golog_rotations_total Files rotated.\n# TYPE golog_rotations_total counter\n")
+	fmt.Fprintf(writer, "golog_rotations_total %d\n", source.Rotations())
+	return writer.Flush()
+}
@@ -0,0 +1,43 @@
+package golog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFileBackendImplementsMetricsSource(t *testing.T) {
+	var _ MetricsSource = (*FileBackend)(nil)
+}
+
+func TestWritePrometheusText(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+
+	fileBackend.Log(Info, []byte("0123456789\n"))
+	fileBackend.Log(Info, []byte("0123456789\n"))
+	fileBackend.Log(Error, []byte("error\n"))
+	if err := fileBackend.RotateNow(); err != nil {
+		t.Fatalf("rotate failed, err: %v", err)
+	}
+	if actual := fileBackend.Rotations(); actual != uint64(len(levels())) {
+		t.Errorf("rotations not match, expect: %v, actual: %v", len(levels()), actual)
+	}
+
+	var output bytes.Buffer
+	if err := WritePrometheusText(&output, fileBackend); err != nil {
+		t.Fatalf("write metrics failed, err: %v", err)
+	}
+	for _, expect := range []string{
+		"# TYPE golog_lines_total counter\n",
+		"golog_lines_total{level=\"INFO\"} 2\n",
+		"golog_bytes_total{level=\"INFO\"} 22\n",
+		"golog_lines_total{level=\"ERROR\"} 1\n",
+		"golog_dropped_total{level=\"ERROR\"} 0\n",
+		"golog_rotations_total 5\n",
+	} {
+		if !strings.Contains(output.String(), expect) {
+			t.Errorf("metrics should contain %q, actual: %s", expect, output.String())
+		}
+	}
+}
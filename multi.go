@@ -0,0 +1,59 @@
+package golog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrorLogger is implemented by backends which report the failure of a
+// single write, like FileBackend.
+type ErrorLogger interface {
+	LogE(level Level, content []byte) error
+}
+
+// MultiBackend writes every entry into all of its backends, a failed backend
+// does not stop the others.
+type MultiBackend struct {
+	backends []Backend
+}
+
+func NewMultiBackend(backends ...Backend) *MultiBackend {
+	return &MultiBackend{
+		backends: append([]Backend(nil), backends...),
+	}
+}
+
+// LogE writes content into every backend and joins the errors reported by
+// them.
+func (s *MultiBackend) LogE(level Level, content []byte) error {
+	var errs []error
+	for _, backend := range s.backends {
+		if errorLogger, ok := backend.(ErrorLogger); ok {
+			if err := errorLogger.LogE(level, content); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		backend.Log(level, content)
+	}
+	return errors.Join(errs...)
+}
+
+func (s *MultiBackend) Log(level Level, content []byte) {
+	if err := s.LogE(level, content); err != nil {
+		fmt.Fprintf(os.Stderr, "write multi backend failed: %v", err)
+	}
+}
+
+func (s *MultiBackend) Flush() {
+	for _, backend := range s.backends {
+		backend.Flush()
+	}
+}
+
+func (s *MultiBackend) Close() {
+	for _, backend := range s.backends {
+		backend.Close()
+	}
+}
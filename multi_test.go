@@ -0,0 +1,40 @@
+package golog
+
+import (
+	"errors"
+	"testing"
+)
+
+type failingBackend struct {
+	NopBackend
+}
+
+func (failingBackend) LogE(level Level, content []byte) error {
+	return errTestWrite
+}
+
+func TestMultiBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*MultiBackend)(nil)
+	var _ ErrorLogger = (*MultiBackend)(nil)
+	var _ ErrorLogger = (*FileBackend)(nil)
+}
+
+func TestMultiBackendFailure(t *testing.T) {
+	backend := NewMemoryBackend()
+	multiBackend := NewMultiBackend(failingBackend{}, backend)
+	defer multiBackend.Close()
+
+	err := multiBackend.LogE(Info, []byte("This is one string.\n"))
+	if !errors.Is(err, errTestWrite) {
+		t.Errorf("error should be %v, actual: %v", errTestWrite, err)
+	}
+	multiBackend.Log(Error, []byte("This is another string.\n"))
+
+	entries := backend.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries count not match, expect: 2, actual: %v", len(entries))
+	}
+	if expect := "This is one string.\n"; string(entries[0].Content) != expect {
+		t.Errorf("entry not match, expect: %s, actual: %s", expect, entries[0].Content)
+	}
+}
@@ -0,0 +1,252 @@
+package golog
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultNetBufferSize      = 4 * 1024
+	defaultNetMaxPendingBytes = 1024 * 1024
+	defaultNetDialTimeout     = time.Second * 3
+	defaultNetWriteTimeout    = time.Second * 3
+	pendingRetryInterval      = time.Millisecond * 50
+	minNetReconnectDelay      = time.Millisecond * 100
+	maxNetReconnectDelay      = time.Second * 30
+)
+
+// NetBackend sends log lines to a TCP or UDP endpoint. Lines are buffered
+// in memory while the connection is down and sent after reconnecting, the
+// reconnects back off while the endpoint stays unreachable.
+type NetBackend struct {
+	mutex           sync.Mutex
+	network         string
+	addr            string
+	conn            net.Conn
+	pending         []byte
+	maxPendingBytes int
+	pendingTimeout  time.Duration
+	dropped         uint64
+	done            chan struct{}
+	reconnectDelay  time.Duration
+	reconnectTime   time.Time
+	dial            func(network, addr string, timeout time.Duration) (net.Conn, error)
+}
+
+func NewNetBackend(network, addr string) (*NetBackend, error) {
+	netBackend := &NetBackend{
+		network:         network,
+		addr:            addr,
+		maxPendingBytes: defaultNetMaxPendingBytes,
+		done:            make(chan struct{}),
+		dial:            net.DialTimeout,
+	}
+	if err := netBackend.connect(time.Time{}); err != nil {
+		return nil, err
+	}
+	go netBackend.flushLoop(defaultFlushInterval)
+	return netBackend, nil
+}
+
+// SetMaxPendingBytes caps the bytes buffered while the endpoint is
+// unreachable, lines beyond the cap are dropped.
+func (s *NetBackend) SetMaxPendingBytes(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxPendingBytes = n
+}
+
+// SetPendingTimeout makes Log wait up to d for the pending lines to be sent
+// when the cap of pending bytes is reached, instead of dropping the line at
+// once. The line is dropped if it still does not fit after d.
+func (s *NetBackend) SetPendingTimeout(d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pendingTimeout = d
+}
+
+func (s *NetBackend) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *NetBackend) flushLoop(d time.Duration) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.Flush()
+		}
+	}
+}
+
+// connect dials the endpoint unless the last dial failed recently, giving up
+// at deadline, zero means no deadline.
+func (s *NetBackend) connect(deadline time.Time) error {
+	now := time.Now()
+	if now.Before(s.reconnectTime) {
+		return fmt.Errorf("reconnect delayed for %v", s.reconnectTime.Sub(now))
+	}
+	timeout := defaultNetDialTimeout
+	if !deadline.IsZero() && deadline.Sub(now) < timeout {
+		timeout = deadline.Sub(now)
+	}
+	conn, err := s.dial(s.network, s.addr, timeout)
+	if err != nil {
+		s.reconnectDelay *= 2
+		if s.reconnectDelay < minNetReconnectDelay {
+			s.reconnectDelay = minNetReconnectDelay
+		}
+		if s.reconnectDelay > maxNetReconnectDelay {
+			s.reconnectDelay = maxNetReconnectDelay
+		}
+		s.reconnectTime = now.Add(s.reconnectDelay)
+		return err
+	}
+	s.reconnectDelay = 0
+	s.conn = conn
+	return nil
+}
+
+func (s *NetBackend) disconnect() {
+	if s.conn == nil {
+		return
+	}
+	s.conn.Close()
+	s.conn = nil
+}
+
+// flush sends the pending lines, reconnecting once if the connection is
+// broken.
+func (s *NetBackend) flush() error {
+	return s.flushBefore(time.Time{})
+}
+
+// flushBefore is flush giving up writing at deadline, zero means the default
+// write timeout.
+func (s *NetBackend) flushBefore(deadline time.Time) error {
+	var err error
+	for retry := 0; retry < 2 && len(s.pending) > 0; retry++ {
+		if s.conn == nil {
+			if err = s.connect(deadline); err != nil {
+				return err
+			}
+		}
+		writeDeadline := deadline
+		if writeDeadline.IsZero() {
+			writeDeadline = time.Now().Add(defaultNetWriteTimeout)
+		}
+		s.conn.SetWriteDeadline(writeDeadline)
+		var n int
+		n, err = s.conn.Write(s.pending)
+		s.pending = s.pending[n:]
+		if err != nil {
+			s.disconnect()
+			continue
+		}
+	}
+	if len(s.pending) == 0 {
+		s.pending = nil
+	}
+	return err
+}
+
+func (s *NetBackend) Log(level Level, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.isClosed() {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	lineSize := len(content)
+	if lineSize == 0 || content[lineSize-1] != '\n' {
+		lineSize++
+	}
+	if len(s.pending)+lineSize > s.maxPendingBytes && !s.waitPending(lineSize) {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	s.pending = append(s.pending, content...)
+	if lineSize > len(content) {
+		s.pending = append(s.pending, '\n')
+	}
+	// reconnecting is left to the flush loop, not to block the callers.
+	if len(s.pending) >= defaultNetBufferSize && s.conn != nil {
+		if err := s.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "send to %s failed: %v", s.addr, err)
+		}
+	}
+}
+
+// waitPending sends the pending lines until lineSize more bytes fit in the
+// cap or the pending timeout passes. The mutex is released while waiting
+// to retry.
+func (s *NetBackend) waitPending(lineSize int) bool {
+	if s.pendingTimeout <= 0 {
+		return false
+	}
+	deadline := time.Now().Add(s.pendingTimeout)
+	for {
+		if err := s.flushBefore(deadline); err != nil {
+			fmt.Fprintf(os.Stderr, "send to %s failed: %v", s.addr, err)
+		}
+		if len(s.pending)+lineSize <= s.maxPendingBytes {
+			return true
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return false
+		}
+		if wait > pendingRetryInterval {
+			wait = pendingRetryInterval
+		}
+		s.mutex.Unlock()
+		time.Sleep(wait)
+		s.mutex.Lock()
+		if s.isClosed() {
+			return false
+		}
+	}
+}
+
+func (s *NetBackend) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *NetBackend) Flush() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// the lines left by the last flush of Close are dropped.
+	if s.isClosed() {
+		return
+	}
+	if err := s.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "send to %s failed: %v", s.addr, err)
+	}
+}
+
+func (s *NetBackend) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+		close(s.done)
+	}
+	if err := s.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "send to %s failed: %v", s.addr, err)
+	}
+	s.disconnect()
+}
@@ -0,0 +1,219 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func acceptLines(t *testing.T, listener net.Listener) *bufio.Scanner {
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("accept failed, err: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(time.Second * 3))
+	return bufio.NewScanner(conn)
+}
+
+func expectLines(t *testing.T, scanner *bufio.Scanner, lines ...string) {
+	for _, expect := range lines {
+		if !scanner.Scan() {
+			t.Fatalf("read line failed, err: %v", scanner.Err())
+		}
+		if scanner.Text() != expect {
+			t.Errorf("line not match, expect: %s, actual: %s", expect, scanner.Text())
+		}
+	}
+}
+
+func TestNetBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*NetBackend)(nil)
+}
+
+func TestNetBackend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, err: %v", err)
+	}
+	defer listener.Close()
+
+	netBackend, err := NewNetBackend("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("create net backend failed, err: %v", err)
+	}
+	defer netBackend.Close()
+	scanner := acceptLines(t, listener)
+
+	netBackend.Log(Info, []byte("first"))
+	netBackend.Log(Error, []byte("second\n"))
+	netBackend.Flush()
+	expectLines(t, scanner, "first", "second")
+
+	// broken connection is reestablished on next flush.
+	netBackend.conn.Close()
+	netBackend.Log(Info, []byte("third"))
+	netBackend.Flush()
+	expectLines(t, acceptLines(t, listener), "third")
+}
+
+func TestNetBackendPendingCap(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, err: %v", err)
+	}
+	netBackend, err := NewNetBackend("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("create net backend failed, err: %v", err)
+	}
+	defer netBackend.Close()
+
+	// endpoint goes away, lines are buffered up to the cap.
+	listener.Close()
+	netBackend.disconnect()
+	netBackend.SetMaxPendingBytes(11)
+	netBackend.Log(Info, []byte("01234"))
+	netBackend.Log(Info, []byte("5678"))
+	netBackend.Log(Info, []byte("9"))
+	netBackend.Flush()
+
+	if string(netBackend.pending) != "01234\n5678\n" {
+		t.Errorf("pending not match, actual: %q", netBackend.pending)
+	}
+	if netBackend.Dropped() != 1 {
+		t.Errorf("dropped should be 1, actual: %v", netBackend.Dropped())
+	}
+}
+
+func TestNetBackendPendingTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, err: %v", err)
+	}
+	defer listener.Close()
+	netBackend, err := NewNetBackend("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("create net backend failed, err: %v", err)
+	}
+	defer netBackend.Close()
+
+	// writes into the pipe block until the slow endpoint reads.
+	client, server := net.Pipe()
+	defer server.Close()
+	netBackend.disconnect()
+	netBackend.conn = client
+	netBackend.SetMaxPendingBytes(11)
+	netBackend.SetPendingTimeout(time.Second * 3)
+	delay := time.Millisecond * 100
+	go func() {
+		time.Sleep(delay)
+		io.Copy(ioutil.Discard, server)
+	}()
+
+	netBackend.Log(Info, []byte("01234"))
+	start := time.Now()
+	netBackend.Log(Info, []byte("56789"))
+	netBackend.Log(Info, []byte("abcde"))
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("log should block until the endpoint reads, elapsed: %v", elapsed)
+	}
+	if netBackend.Dropped() != 0 {
+		t.Errorf("dropped should be 0, actual: %v", netBackend.Dropped())
+	}
+	if string(netBackend.pending) != "abcde\n" {
+		t.Errorf("pending not match, actual: %q", netBackend.pending)
+	}
+}
+
+func TestNetBackendPendingTimeoutExpired(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, err: %v", err)
+	}
+	netBackend, err := NewNetBackend("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("create net backend failed, err: %v", err)
+	}
+	defer netBackend.Close()
+
+	listener.Close()
+	netBackend.disconnect()
+	netBackend.SetMaxPendingBytes(6)
+	netBackend.SetPendingTimeout(time.Millisecond * 100)
+	netBackend.Log(Info, []byte("01234"))
+	start := time.Now()
+	netBackend.Log(Info, []byte("56789"))
+	if elapsed := time.Since(start); elapsed < time.Millisecond*100 {
+		t.Errorf("log should block until timeout, elapsed: %v", elapsed)
+	}
+	if netBackend.Dropped() != 1 {
+		t.Errorf("dropped should be 1, actual: %v", netBackend.Dropped())
+	}
+}
+
+func TestNetBackendLogAfterClose(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, err: %v", err)
+	}
+	defer listener.Close()
+	netBackend, err := NewNetBackend("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("create net backend failed, err: %v", err)
+	}
+	netBackend.Close()
+
+	netBackend.Log(Info, bytes.Repeat([]byte("x"), defaultNetBufferSize))
+	if netBackend.conn != nil {
+		t.Errorf("log after close should not reconnect")
+	}
+	// pending lines left by a failed flush in Close.
+	netBackend.pending = []byte("left\n")
+	netBackend.Flush()
+	if netBackend.conn != nil {
+		t.Errorf("flush after close should not reconnect")
+	}
+	if netBackend.Dropped() != 1 {
+		t.Errorf("dropped should be 1, actual: %v", netBackend.Dropped())
+	}
+}
+
+func TestNetBackendReconnectBackoff(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, err: %v", err)
+	}
+	netBackend, err := NewNetBackend("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("create net backend failed, err: %v", err)
+	}
+	defer netBackend.Close()
+	dials := 0
+	netBackend.dial = func(network, addr string, timeout time.Duration) (net.Conn, error) {
+		dials++
+		return net.DialTimeout(network, addr, timeout)
+	}
+
+	listener.Close()
+	netBackend.disconnect()
+	// logs do not dial, and the flushes back off after a failed dial.
+	for i := 0; i < 3; i++ {
+		netBackend.Log(Info, bytes.Repeat([]byte("x"), defaultNetBufferSize))
+		netBackend.Flush()
+	}
+	if dials != 1 {
+		t.Errorf("dials should be 1, actual: %v", dials)
+	}
+	netBackend.reconnectTime = time.Time{}
+	netBackend.Flush()
+	if dials != 2 {
+		t.Errorf("dials should be 2, actual: %v", dials)
+	}
+	if netBackend.reconnectDelay != minNetReconnectDelay*2 {
+		t.Errorf("reconnect delay should double, actual: %v", netBackend.reconnectDelay)
+	}
+}
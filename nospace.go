@@ -0,0 +1,12 @@
+//go:build !plan9
+
+package golog
+
+import (
+	"errors"
+	"syscall"
+)
+
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
@@ -0,0 +1,8 @@
+package golog
+
+import "strings"
+
+// plan9 reports errors as strings.
+func isNoSpace(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "file system full")
+}
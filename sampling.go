@@ -0,0 +1,92 @@
+package golog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type samplingWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// SamplingBackend passes at most perInterval entries of each level to the
+// wrapped backend in every interval, the rest are dropped.
+type SamplingBackend struct {
+	mutex       sync.Mutex
+	backend     Backend
+	perInterval int
+	interval    time.Duration
+	summary     bool
+	windows     map[Level]*samplingWindow
+
+	getNowTime func() time.Time
+}
+
+func NewSamplingBackend(b Backend, perInterval int, interval time.Duration) *SamplingBackend {
+	return &SamplingBackend{
+		backend:     b,
+		perInterval: perInterval,
+		interval:    interval,
+		summary:     true,
+		windows:     make(map[Level]*samplingWindow),
+		getNowTime:  time.Now,
+	}
+}
+
+// SetSummary controls whether a "(suppressed N messages)" line is written
+// when an interval with dropped entries ends.
+func (s *SamplingBackend) SetSummary(enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.summary = enabled
+}
+
+func (s *SamplingBackend) Log(level Level, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	nowTime := s.getNowTime()
+	window, ok := s.windows[level]
+	if !ok {
+		window = &samplingWindow{start: nowTime}
+		s.windows[level] = window
+	} else if nowTime.Sub(window.start) >= s.interval {
+		s.writeSummary(level, window)
+		window.start = nowTime
+		window.count = 0
+	}
+
+	if window.count >= s.perInterval {
+		window.suppressed++
+		return
+	}
+	window.count++
+	s.backend.Log(level, content)
+}
+
+func (s *SamplingBackend) writeSummary(level Level, window *samplingWindow) {
+	if s.summary && window.suppressed > 0 {
+		s.backend.Log(level, []byte(fmt.Sprintf("(suppressed %d messages)\n", window.suppressed)))
+	}
+	window.suppressed = 0
+}
+
+func (s *SamplingBackend) Flush() {
+	s.backend.Flush()
+}
+
+// Close writes the summaries of the current intervals before closing the
+// wrapped backend.
+func (s *SamplingBackend) Close() {
+	s.mutex.Lock()
+	for _, level := range levels() {
+		if window, ok := s.windows[level]; ok {
+			s.writeSummary(level, window)
+		}
+	}
+	s.mutex.Unlock()
+	s.backend.Close()
+}
@@ -0,0 +1,77 @@
+package golog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSamplingBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*SamplingBackend)(nil)
+}
+
+func TestSamplingBackendFlood(t *testing.T) {
+	backend := NewMemoryBackend()
+	samplingBackend := NewSamplingBackend(backend, 3, time.Second)
+	nowTime := time.Date(2019, 7, 10, 1, 13, 14, 0, time.UTC)
+	samplingBackend.getNowTime = func() time.Time {
+		return nowTime
+	}
+
+	for i := 0; i < 100; i++ {
+		samplingBackend.Log(Error, []byte(fmt.Sprintf("error %d\n", i)))
+	}
+	samplingBackend.Log(Info, []byte("info\n"))
+	nowTime = nowTime.Add(time.Second)
+	samplingBackend.Log(Error, []byte("next interval\n"))
+
+	expects := []Entry{
+		{Error, []byte("error 0\n")},
+		{Error, []byte("error 1\n")},
+		{Error, []byte("error 2\n")},
+		{Info, []byte("info\n")},
+		{Error, []byte("(suppressed 97 messages)\n")},
+		{Error, []byte("next interval\n")},
+	}
+	entries := backend.Entries()
+	if len(entries) != len(expects) {
+		t.Fatalf("entries count not match, expect: %v, actual: %v", len(expects), len(entries))
+	}
+	for i, expect := range expects {
+		if entries[i].Level != expect.Level || string(entries[i].Content) != string(expect.Content) {
+			t.Errorf("entry not match, expect: %v %s, actual: %v %s",
+				expect.Level, expect.Content, entries[i].Level, entries[i].Content)
+		}
+	}
+}
+
+func TestSamplingBackendSummaryOnClose(t *testing.T) {
+	backend := NewMemoryBackend()
+	samplingBackend := NewSamplingBackend(backend, 1, time.Hour)
+	for i := 0; i < 10; i++ {
+		samplingBackend.Log(Warning, []byte("warning\n"))
+	}
+	samplingBackend.Close()
+
+	entries := backend.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries count not match, expect: 2, actual: %v", len(entries))
+	}
+	if expect := "(suppressed 9 messages)\n"; string(entries[1].Content) != expect {
+		t.Errorf("summary not match, expect: %s, actual: %s", expect, entries[1].Content)
+	}
+}
+
+func TestSamplingBackendWithoutSummary(t *testing.T) {
+	backend := NewMemoryBackend()
+	samplingBackend := NewSamplingBackend(backend, 1, time.Hour)
+	samplingBackend.SetSummary(false)
+	for i := 0; i < 10; i++ {
+		samplingBackend.Log(Warning, []byte("warning\n"))
+	}
+	samplingBackend.Close()
+
+	if entries := backend.Entries(); len(entries) != 1 {
+		t.Errorf("entries count not match, expect: 1, actual: %v", len(entries))
+	}
+}
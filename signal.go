@@ -0,0 +1,47 @@
+package golog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// raiseSignal delivers sig to the process again once the backend is closed,
+// so the signal keeps its default behavior, like exiting on os.Interrupt.
+var raiseSignal = func(sig os.Signal) {
+	if process, err := os.FindProcess(os.Getpid()); err == nil {
+		process.Signal(sig)
+	}
+}
+
+// InstallSignalHandler flushes and closes backend when one of signals,
+// os.Interrupt by default, is received, then delivers the signal again. The
+// returned function uninstalls the handler.
+func InstallSignalHandler(backend Backend, signals ...os.Signal) func() {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt}
+	}
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	stop := make(chan struct{})
+	go handleSignals(backend, received, stop)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(received)
+			close(stop)
+		})
+	}
+}
+
+func handleSignals(backend Backend, received chan os.Signal, stop <-chan struct{}) {
+	select {
+	case sig := <-received:
+		backend.Flush()
+		backend.Close()
+		signal.Stop(received)
+		raiseSignal(sig)
+	case <-stop:
+	}
+}
@@ -0,0 +1,72 @@
+package golog
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type spyBackend struct {
+	NopBackend
+	mutex   sync.Mutex
+	flushed int
+	closed  int
+}
+
+func (s *spyBackend) Flush() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.flushed++
+}
+
+func (s *spyBackend) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed++
+}
+
+func TestHandleSignals(t *testing.T) {
+	raised := make(chan os.Signal, 1)
+	defaultRaiseSignal := raiseSignal
+	raiseSignal = func(sig os.Signal) {
+		raised <- sig
+	}
+	defer func() {
+		raiseSignal = defaultRaiseSignal
+	}()
+
+	backend := &spyBackend{}
+	received := make(chan os.Signal, 1)
+	go handleSignals(backend, received, make(chan struct{}))
+	received <- os.Interrupt
+
+	select {
+	case sig := <-raised:
+		if sig != os.Interrupt {
+			t.Errorf("raised signal not match, expect: %v, actual: %v", os.Interrupt, sig)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("signal should be raised again")
+	}
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+	if backend.flushed != 1 || backend.closed != 1 {
+		t.Errorf("backend should be flushed and closed once, flushed: %v, closed: %v",
+			backend.flushed, backend.closed)
+	}
+}
+
+func TestUninstallSignalHandler(t *testing.T) {
+	backend := &spyBackend{}
+	uninstall := InstallSignalHandler(backend)
+	uninstall()
+	uninstall()
+
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+	if backend.flushed != 0 || backend.closed != 0 {
+		t.Errorf("backend should not be touched, flushed: %v, closed: %v",
+			backend.flushed, backend.closed)
+	}
+}
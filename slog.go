@@ -0,0 +1,102 @@
+package golog
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+type slogHandler struct {
+	backend     Backend
+	attrs       string
+	groupPrefix string
+}
+
+// NewSlogHandler returns a slog.Handler writing records into backend, the
+// attributes are rendered as key=value pairs after the message.
+func NewSlogHandler(backend Backend) slog.Handler {
+	return &slogHandler{backend: backend}
+}
+
+func levelFromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return Debug
+	case level < slog.LevelWarn:
+		return Info
+	case level < slog.LevelError:
+		return Warning
+	default:
+		return Error
+	}
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if enabler, ok := h.backend.(LevelEnabler); ok {
+		return enabler.IsLevelEnabled(levelFromSlog(level))
+	}
+	return true
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	var builder strings.Builder
+	builder.WriteString(record.Message)
+	builder.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		appendSlogAttr(&builder, h.groupPrefix, attr)
+		return true
+	})
+	builder.WriteByte('\n')
+	h.backend.Log(levelFromSlog(record.Level), withTracePrefix(ctx, []byte(builder.String())))
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var builder strings.Builder
+	builder.WriteString(h.attrs)
+	for _, attr := range attrs {
+		appendSlogAttr(&builder, h.groupPrefix, attr)
+	}
+	handler := *h
+	handler.attrs = builder.String()
+	return &handler
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.groupPrefix = h.groupPrefix + name + "."
+	return &handler
+}
+
+func appendSlogAttr(builder *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			appendSlogAttr(builder, groupPrefix, groupAttr)
+		}
+		return
+	}
+	builder.WriteByte(' ')
+	builder.WriteString(prefix)
+	builder.WriteString(attr.Key)
+	builder.WriteByte('=')
+	builder.WriteString(quoteIfNeeded(attr.Value.String()))
+}
+
+func quoteIfNeeded(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\t\r\n") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,66 @@
+package golog
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogHandlerLevel(t *testing.T) {
+	backend := NewMemoryBackend()
+	logger := slog.New(NewSlogHandler(backend))
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+
+	expect := []Level{Debug, Info, Warning, Error}
+	entries := backend.Entries()
+	if len(entries) != len(expect) {
+		t.Fatalf("count of entries should be %v, actual: %v", len(expect), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Level != expect[i] {
+			t.Errorf("level not match, expect: %v, actual: %v", expect[i], entry.Level)
+		}
+	}
+}
+
+func TestSlogHandlerAttrs(t *testing.T) {
+	backend := NewMemoryBackend()
+	logger := slog.New(NewSlogHandler(backend))
+
+	logger.Info("hello", "user", "alice", "count", 3, "note", "two words")
+	logger.With("service", "api").WithGroup("req").With("id", 7).
+		Warn("slow", "ms", 1500, slog.Group("peer", "ip", "127.0.0.1"))
+
+	expect := []string{
+		"hello user=alice count=3 note=\"two words\"\n",
+		"slow service=api req.id=7 req.ms=1500 req.peer.ip=127.0.0.1\n",
+	}
+	entries := backend.Entries()
+	if len(entries) != len(expect) {
+		t.Fatalf("count of entries should be %v, actual: %v", len(expect), len(entries))
+	}
+	for i, entry := range entries {
+		if string(entry.Content) != expect[i] {
+			t.Errorf("content not match, expect: %q, actual: %q", expect[i], entry.Content)
+		}
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	fileBackend := createFileBackend(t)
+	defer fileBackend.Close()
+	if err := fileBackend.SetMinLevel(Warning); err != nil {
+		t.Fatalf("set min level failed, err: %v", err)
+	}
+	logger := slog.New(NewSlogHandler(fileBackend))
+	if logger.Enabled(context.Background(), slog.LevelInfo) {
+		t.Errorf("info should be disabled")
+	}
+	if !logger.Enabled(context.Background(), slog.LevelError) {
+		t.Errorf("error should be enabled")
+	}
+}
@@ -0,0 +1,127 @@
+//go:build !windows && !plan9
+
+package golog
+
+import (
+	"fmt"
+	"log/syslog"
+	"os"
+	"sync"
+)
+
+var (
+	syslogSeverities = map[Level]syslog.Priority{
+		Debug:   syslog.LOG_DEBUG,
+		Info:    syslog.LOG_INFO,
+		Warning: syslog.LOG_WARNING,
+		Error:   syslog.LOG_ERR,
+		Fatal:   syslog.LOG_CRIT,
+	}
+)
+
+type SyslogBackend struct {
+	mutex   sync.Mutex
+	network string
+	addr    string
+	tag     string
+	writer  *syslog.Writer
+	closed  bool
+}
+
+func NewSyslogBackend(network, addr, tag string) (*SyslogBackend, error) {
+	syslogBackend := &SyslogBackend{
+		network: network,
+		addr:    addr,
+		tag:     tag,
+	}
+	if err := syslogBackend.connect(); err != nil {
+		return nil, err
+	}
+	return syslogBackend, nil
+}
+
+func (s *SyslogBackend) connect() error {
+	writer, err := syslog.Dial(s.network, s.addr, syslog.LOG_USER|syslog.LOG_INFO, s.tag)
+	if err != nil {
+		return err
+	}
+	s.writer = writer
+	return nil
+}
+
+func syslogSeverity(level Level) syslog.Priority {
+	if severity, ok := syslogSeverities[level]; ok {
+		return severity
+	}
+	// custom levels take the severity of the builtin level below them.
+	for builtin := levelMax; builtin >= levelMin; builtin-- {
+		if !level.below(builtin) {
+			return syslogSeverities[builtin]
+		}
+	}
+	return syslog.LOG_DEBUG
+}
+
+func (s *SyslogBackend) write(level Level, content string) error {
+	switch syslogSeverity(level) {
+	case syslog.LOG_DEBUG:
+		return s.writer.Debug(content)
+	case syslog.LOG_WARNING:
+		return s.writer.Warning(content)
+	case syslog.LOG_ERR:
+		return s.writer.Err(content)
+	case syslog.LOG_CRIT:
+		return s.writer.Crit(content)
+	default:
+		return s.writer.Info(content)
+	}
+}
+
+func (s *SyslogBackend) Log(level Level, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !isValidLevel(level) {
+		fmt.Fprintf(os.Stderr, "invalid level: %v, content: %s", level, content)
+		return
+	}
+	// nothing closes the connection made after Close.
+	if s.closed {
+		fmt.Fprintf(os.Stderr, "syslog backend is closed, content: %s", content)
+		return
+	}
+
+	// reconnect if the previous connection was dropped.
+	if s.writer == nil {
+		if err := s.connect(); err != nil {
+			fmt.Fprintf(os.Stderr, "connect syslog failed: %v, content: %s", err, content)
+			return
+		}
+	}
+	if err := s.write(level, string(content)); err != nil {
+		s.writer.Close()
+		s.writer = nil
+		if err := s.connect(); err != nil {
+			fmt.Fprintf(os.Stderr, "connect syslog failed: %v, content: %s", err, content)
+			return
+		}
+		if err := s.write(level, string(content)); err != nil {
+			fmt.Fprintf(os.Stderr, "write syslog failed: %v, content: %s", err, content)
+		}
+	}
+}
+
+func (s *SyslogBackend) Flush() {
+}
+
+func (s *SyslogBackend) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	if s.writer == nil {
+		return
+	}
+	if err := s.writer.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "close syslog failed: %v", err)
+	}
+	s.writer = nil
+}
@@ -0,0 +1,97 @@
+//go:build !windows && !plan9
+
+package golog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log/syslog"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogBackendImplementsBackend(t *testing.T) {
+	var _ Backend = (*SyslogBackend)(nil)
+}
+
+func TestSyslogBackendSeverity(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "syslogBackend_test")
+	if err != nil {
+		t.Fatalf("create temporary directoey failed, err: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	socketPath := path.Join(tempDir, "syslog.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen %s failed, err: %v", socketPath, err)
+	}
+	defer conn.Close()
+
+	syslogBackend, err := NewSyslogBackend("unixgram", socketPath, "golog")
+	if err != nil {
+		t.Fatalf("create syslog backend failed, err: %v", err)
+	}
+	defer syslogBackend.Close()
+
+	buffer := make([]byte, 4096)
+	for level := levelMin; level <= levelMax; level++ {
+		outputContent := fmt.Sprintf("This is a %s string.", levelNames[level])
+		syslogBackend.Log(level, []byte(outputContent))
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buffer)
+		if err != nil {
+			t.Fatalf("read syslog message failed, err: %v", err)
+		}
+		message := string(buffer[:n])
+		expectPrefix := fmt.Sprintf("<%d>", syslog.LOG_USER|syslogSeverities[level])
+		if !strings.HasPrefix(message, expectPrefix) {
+			t.Errorf("%s severity not match, expect prefix: %s, message: %s",
+				levelNames[level], expectPrefix, message)
+		}
+		if !strings.Contains(message, "golog") || !strings.Contains(message, outputContent) {
+			t.Errorf("%s message not match, message: %s", levelNames[level], message)
+		}
+	}
+
+	// drop the connection, next log should reconnect.
+	syslogBackend.writer.Close()
+	syslogBackend.writer = nil
+	syslogBackend.Log(Info, []byte("This is a reconnected string."))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buffer)
+	if err != nil {
+		t.Fatalf("read syslog message after reconnect failed, err: %v", err)
+	}
+	if message := string(buffer[:n]); !strings.Contains(message, "This is a reconnected string.") {
+		t.Errorf("message not match after reconnect, message: %s", message)
+	}
+}
+
+func TestSyslogBackendLogAfterClose(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "syslogBackend_test")
+	if err != nil {
+		t.Fatalf("create temporary directoey failed, err: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	socketPath := path.Join(tempDir, "syslog.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen %s failed, err: %v", socketPath, err)
+	}
+	defer conn.Close()
+
+	syslogBackend, err := NewSyslogBackend("unixgram", socketPath, "golog")
+	if err != nil {
+		t.Fatalf("create syslog backend failed, err: %v", err)
+	}
+	syslogBackend.Close()
+	syslogBackend.Log(Info, []byte("after close"))
+	if syslogBackend.writer != nil {
+		t.Errorf("log after close should not reconnect")
+	}
+}